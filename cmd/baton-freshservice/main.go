@@ -6,6 +6,7 @@ import (
 	"net/url"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/conductorone/baton-freshservice/pkg/client"
 	"github.com/conductorone/baton-freshservice/pkg/config"
@@ -81,12 +82,22 @@ func getConnector(ctx context.Context, cfg *config.Freshservice) (types.Connecto
 		return nil, fmt.Errorf("invalid subdomain format: %q - should be just the subdomain portion (e.g., 'company' not 'company.freshservice.com')", fsDomain)
 	}
 
-	fsClient = fsClient.WithBearerToken(cfg.ApiKey).WithDomain(fsDomain).WithCategoryID(cfg.CategoryId).WithBaseURL(cfg.BaseUrl)
+	fsClient = fsClient.WithBearerToken(cfg.ApiKey).
+		WithDomain(fsDomain).
+		WithCategoryID(cfg.CategoryId).
+		WithWorkspaceIDs(cfg.WorkspaceIds).
+		WithBaseURL(cfg.BaseUrl).
+		WithRateLimitPercent(cfg.RateLimitPercent).
+		WithProxyURL(cfg.ProxyUrl).
+		WithCABundle(cfg.CaBundlePath).
+		WithClientCertificate(cfg.ClientCertPath, cfg.ClientKeyPath)
 
 	cb, err := connector.New(ctx,
 		cfg.ApiKey,
 		fsDomain,
 		fsClient,
+		connector.WithIncrementalSync(cfg.SyncStatePath, time.Duration(cfg.FullSyncIntervalHours)*time.Hour),
+		connector.WithForgetDeletedRequesters(cfg.ForgetDeletedRequesters),
 	)
 	if err != nil {
 		l.Error("error creating connector", zap.Error(err))
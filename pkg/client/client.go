@@ -8,22 +8,32 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/metrics"
 	"github.com/conductorone/baton-sdk/pkg/uhttp"
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
-	"github.com/tomnomnom/linkheader"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 type FreshServiceClient struct {
-	httpClient *uhttp.BaseHttpClient
-	auth       *auth
-	baseUrl    string
-	domain     string
-	categoryId string
+	httpClient       *uhttp.BaseHttpClient
+	auth             *auth
+	baseUrl          string
+	domain           string
+	categoryId       string
+	workspaceIDs     []string
+	rateLimitPercent int
+	rateGovernor     *rateGovernor
+	transport        transportConfig
+	tracerProvider   trace.TracerProvider
+	metricsHandler   metrics.Handler
+	telemetry        *telemetry
 }
 
 func NewClient(baseClient *uhttp.BaseHttpClient) *FreshServiceClient {
@@ -33,17 +43,10 @@ func NewClient(baseClient *uhttp.BaseHttpClient) *FreshServiceClient {
 		auth: &auth{
 			bearerToken: "",
 		},
+		rateGovernor: newRateGovernor(0),
 	}
 }
 
-type errorResponse struct {
-	MessageContent string `json:"message"`
-}
-
-func (er *errorResponse) Message() string {
-	return fmt.Sprintf("Error: %s", er.MessageContent)
-}
-
 func (f *FreshServiceClient) WithBearerToken(apiToken string) *FreshServiceClient {
 	f.auth.bearerToken = apiToken
 	return f
@@ -64,6 +67,37 @@ func (f *FreshServiceClient) WithBaseURL(baseURL string) *FreshServiceClient {
 	return f
 }
 
+// WithRateLimitPercent caps the client to a percentage (1-100) of the account's per-minute API budget,
+// which Freshservice shares between every integration on the account.
+func (f *FreshServiceClient) WithRateLimitPercent(percent int) *FreshServiceClient {
+	f.rateLimitPercent = percent
+	f.rateGovernor = newRateGovernor(percent)
+	return f
+}
+
+// WithWorkspaceIDs restricts syncing and ticketing to the given workspaces. All workspaces are used when empty.
+func (f *FreshServiceClient) WithWorkspaceIDs(workspaceIDs []string) *FreshServiceClient {
+	f.workspaceIDs = workspaceIDs
+	return f
+}
+
+// WithTracerProvider sets where operation spans are sent. The global OpenTelemetry provider is used by default.
+func (f *FreshServiceClient) WithTracerProvider(tracerProvider trace.TracerProvider) *FreshServiceClient {
+	f.tracerProvider = tracerProvider
+	return f
+}
+
+// WithMetricsHandler sets where operation metrics are recorded. The global OpenTelemetry meter provider is used
+// by default.
+func (f *FreshServiceClient) WithMetricsHandler(handler metrics.Handler) *FreshServiceClient {
+	f.metricsHandler = handler
+	return f
+}
+
+func (f *FreshServiceClient) GetWorkspaceIDs() []string {
+	return f.workspaceIDs
+}
+
 func (f *FreshServiceClient) GetCategoryID() string {
 	return f.categoryId
 }
@@ -95,7 +129,7 @@ func New(ctx context.Context, freshServiceClient *FreshServiceClient) (*FreshSer
 		clientToken = freshServiceClient.getToken()
 		domain      = freshServiceClient.GetDomain()
 	)
-	httpClient, err := uhttp.NewClient(ctx, uhttp.WithLogger(true, ctxzap.Extract(ctx)))
+	httpClient, err := freshServiceClient.transport.newHTTPClient(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -115,10 +149,17 @@ func New(ctx context.Context, freshServiceClient *FreshServiceClient) (*FreshSer
 
 	// bearerToken
 	fs := FreshServiceClient{
-		httpClient: cli,
-		baseUrl:    baseUrl,
-		domain:     domain,
-		categoryId: freshServiceClient.GetCategoryID(),
+		httpClient:       cli,
+		baseUrl:          baseUrl,
+		domain:           domain,
+		categoryId:       freshServiceClient.GetCategoryID(),
+		workspaceIDs:     freshServiceClient.GetWorkspaceIDs(),
+		rateLimitPercent: freshServiceClient.rateLimitPercent,
+		rateGovernor:     newRateGovernor(freshServiceClient.rateLimitPercent),
+		transport:        freshServiceClient.transport,
+		tracerProvider:   freshServiceClient.tracerProvider,
+		metricsHandler:   freshServiceClient.metricsHandler,
+		telemetry:        newTelemetry(ctx, freshServiceClient.tracerProvider, freshServiceClient.metricsHandler),
 		auth: &auth{
 			bearerToken: clientToken,
 		},
@@ -128,59 +169,23 @@ func New(ctx context.Context, freshServiceClient *FreshServiceClient) (*FreshSer
 }
 
 // https://api.freshservice.com/v2/#list_all_requesters
-func (f *FreshServiceClient) ListRequesterUsers(ctx context.Context, opts PageOptions) (*requestersAPIData, string, annotations.Annotations, error) {
-	requestersUrl, err := url.JoinPath(f.baseUrl, "requesters")
-	if err != nil {
-		return nil, "", nil, err
-	}
-	var res *requestersAPIData
-	nextPage, annotation, err := f.getListAPIData(ctx,
-		requestersUrl,
-		&res,
-		WithPage(opts.Page),
-		WithPageLimit(opts.PerPage),
-	)
-	if err != nil {
-		return nil, "", nil, err
-	}
-	return res, nextPage, annotation, nil
+func (f *FreshServiceClient) ListRequesterUsers(ctx context.Context, opts PageOptions, reqOpts ...ReqOpt) (*RequestersAPIData, string, annotations.Annotations, error) {
+	return listPage[RequestersAPIData](withOperation(ctx, "ListRequesterUsers"), f, []string{"requesters"}, opts, reqOpts...)
 }
 
 // https://api.freshservice.com/v2/#list_all_agents
-func (f *FreshServiceClient) ListAgentUsers(ctx context.Context, opts PageOptions) (*AgentsAPIData, string, annotations.Annotations, error) {
-	agentsUrl, err := url.JoinPath(f.baseUrl, "agents")
-	if err != nil {
-		return nil, "", nil, err
-	}
-
-	var res *AgentsAPIData
-	nextPage, annotation, err := f.getListAPIData(ctx, agentsUrl, &res, WithPage(opts.Page), WithPageLimit(opts.PerPage))
-	if err != nil {
-		return nil, "", nil, err
-	}
-
-	return res, nextPage, annotation, nil
+func (f *FreshServiceClient) ListAgentUsers(ctx context.Context, opts PageOptions, reqOpts ...ReqOpt) (*AgentsAPIData, string, annotations.Annotations, error) {
+	return listPage[AgentsAPIData](withOperation(ctx, "ListAgentUsers"), f, []string{"agents"}, opts, reqOpts...)
 }
 
 // https://api.freshservice.com/v2/#view_all_group
-func (f *FreshServiceClient) ListAgentGroups(ctx context.Context, opts PageOptions) (*AgentGroupsAPIData, string, annotations.Annotations, error) {
-	groupsUrl, err := url.JoinPath(f.baseUrl, "groups")
-	if err != nil {
-		return nil, "", nil, err
-	}
-
-	var res *AgentGroupsAPIData
-	nextPage, annotation, err := f.getListAPIData(ctx,
-		groupsUrl,
-		&res,
-		WithPage(opts.Page),
-		WithPageLimit(opts.PerPage),
-	)
-	if err != nil {
-		return nil, "", nil, err
-	}
+func (f *FreshServiceClient) ListAgentGroups(ctx context.Context, opts PageOptions, reqOpts ...ReqOpt) (*AgentGroupsAPIData, string, annotations.Annotations, error) {
+	return listPage[AgentGroupsAPIData](withOperation(ctx, "ListAgentGroups"), f, []string{"groups"}, opts, reqOpts...)
+}
 
-	return res, nextPage, annotation, nil
+// https://api.freshservice.com/v2/#list_all_workspaces
+func (f *FreshServiceClient) ListWorkspaces(ctx context.Context, opts PageOptions) (*WorkspacesAPIData, string, annotations.Annotations, error) {
+	return listPage[WorkspacesAPIData](withOperation(ctx, "ListWorkspaces"), f, []string{"workspaces"}, opts)
 }
 
 func (f *FreshServiceClient) getListAPIData(
@@ -194,39 +199,26 @@ func (f *FreshServiceClient) getListAPIData(
 		return "", annotation, err
 	}
 
-	var pageToken string
-	pagingLinks := linkheader.Parse(header.Get("Link"))
-	for _, link := range pagingLinks {
-		if link.Rel == "next" {
-			nextPageUrl, err := url.Parse(link.URL)
-			if err != nil {
-				return "", nil, err
-			}
-			pageToken = nextPageUrl.Query().Get("page")
-			break
-		}
+	nextPageUrl, err := nextPageLink(header)
+	if err != nil {
+		return "", nil, err
+	}
+	if nextPageUrl == nil {
+		return "", annotation, nil
 	}
 
-	return pageToken, annotation, nil
+	return nextPageUrl.Query().Get("page"), annotation, nil
 }
 
 // https://api.freshservice.com/v2/#view_all_role
 func (f *FreshServiceClient) ListRoles(ctx context.Context, opts PageOptions) (*RolesAPIData, string, annotations.Annotations, error) {
-	rolesUrl, err := url.JoinPath(f.baseUrl, "roles")
-	if err != nil {
-		return nil, "", nil, err
-	}
-	var roles *RolesAPIData
-	nextPage, annos, err := f.getListAPIData(ctx, rolesUrl, &roles, WithPage(opts.Page), WithPageLimit(opts.PerPage))
-	if err != nil {
-		return nil, "", nil, err
-	}
-	return roles, nextPage, annos, nil
+	return listPage[RolesAPIData](withOperation(ctx, "ListRoles"), f, []string{"roles"}, opts)
 }
 
 // GetAgentGroupDetail. List All Agents in a Group.
 // https://api.freshservice.com/v2/#view_a_group
 func (f *FreshServiceClient) GetAgentGroupDetail(ctx context.Context, groupId string) (*AgentGroupDetailAPIData, annotations.Annotations, error) {
+	ctx = withOperation(ctx, "GetAgentGroupDetail")
 	var res *AgentGroupDetailAPIData
 	groupUrl, err := url.JoinPath(f.baseUrl, "groups", groupId)
 	if err != nil {
@@ -242,6 +234,7 @@ func (f *FreshServiceClient) GetAgentGroupDetail(ctx context.Context, groupId st
 // UpdateAgentGroupMembers. Update the existing agent group to add another agent to the group
 // https://api.freshservice.com/v2/#update_a_group
 func (f *FreshServiceClient) UpdateAgentGroupMembers(ctx context.Context, groupId string, usersId []int64) (annotations.Annotations, error) {
+	ctx = withOperation(ctx, "UpdateAgentGroupMembers")
 	groupUrl, err := url.JoinPath(f.baseUrl, "groups", groupId)
 	if err != nil {
 		return nil, err
@@ -258,9 +251,48 @@ func (f *FreshServiceClient) UpdateAgentGroupMembers(ctx context.Context, groupI
 	return annotation, nil
 }
 
+// UpdateAgentGroupObservers replaces the observers of an agent group.
+// https://api.freshservice.com/v2/#update_a_group
+func (f *FreshServiceClient) UpdateAgentGroupObservers(ctx context.Context, groupId string, usersId []int64) (annotations.Annotations, error) {
+	return f.updateAgentGroup(withOperation(ctx, "UpdateAgentGroupObservers"), groupId, &UpdateAgentGroupObservers{Observers: usersId})
+}
+
+// UpdateAgentGroupLeaders replaces the leaders of an agent group.
+// https://api.freshservice.com/v2/#update_a_group
+func (f *FreshServiceClient) UpdateAgentGroupLeaders(ctx context.Context, groupId string, usersId []int64) (annotations.Annotations, error) {
+	return f.updateAgentGroup(withOperation(ctx, "UpdateAgentGroupLeaders"), groupId, &UpdateAgentGroupLeaders{Leaders: usersId})
+}
+
+// UpdateAgentGroupEscalateTo sets the agent unassigned tickets of an agent group escalate to, or clears it when
+// userId is nil.
+// https://api.freshservice.com/v2/#update_a_group
+func (f *FreshServiceClient) UpdateAgentGroupEscalateTo(ctx context.Context, groupId string, userId *int64) (annotations.Annotations, error) {
+	return f.updateAgentGroup(withOperation(ctx, "UpdateAgentGroupEscalateTo"), groupId, &UpdateAgentGroupEscalateTo{EscalateTo: userId})
+}
+
+// UpdateAgentGroupAgents replaces the members, observers, leaders and escalation contact set in update, in a single
+// request.
+// https://api.freshservice.com/v2/#update_a_group
+func (f *FreshServiceClient) UpdateAgentGroupAgents(ctx context.Context, groupId string, update *UpdateAgentGroupAgents) (annotations.Annotations, error) {
+	return f.updateAgentGroup(withOperation(ctx, "UpdateAgentGroupAgents"), groupId, update)
+}
+
+func (f *FreshServiceClient) updateAgentGroup(ctx context.Context, groupId string, body interface{}) (annotations.Annotations, error) {
+	groupUrl, err := url.JoinPath(f.baseUrl, "groups", groupId)
+	if err != nil {
+		return nil, err
+	}
+	_, annotation, err := f.doRequest(ctx, http.MethodPut, groupUrl, nil, body)
+	if err != nil {
+		return nil, err
+	}
+	return annotation, nil
+}
+
 // GetAgentDetail. Get agent detail.
 // https://api.freshservice.com/v2/#view_an_agent
 func (f *FreshServiceClient) GetAgentDetail(ctx context.Context, userId string) (*AgentDetailAPIData, annotations.Annotations, error) {
+	ctx = withOperation(ctx, "GetAgentDetail")
 	agentsUrl, err := url.JoinPath(f.baseUrl, "agents", userId)
 	if err != nil {
 		return nil, nil, err
@@ -275,6 +307,255 @@ func (f *FreshServiceClient) GetAgentDetail(ctx context.Context, userId string)
 	return res, annotation, nil
 }
 
+// CreateAgent creates an agent and returns it.
+// https://api.freshservice.com/v2/#create_an_agent
+func (f *FreshServiceClient) CreateAgent(ctx context.Context, agent *CreateAgent) (*AgentDetailAPIData, annotations.Annotations, error) {
+	ctx = withOperation(ctx, "CreateAgent")
+	agentsUrl, err := url.JoinPath(f.baseUrl, "agents")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var res *AgentDetailAPIData
+	_, annotation, err := f.doRequest(ctx, http.MethodPost, agentsUrl, &res, agent)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return res, annotation, nil
+}
+
+// GetRequesterDetail. View a Requester.
+// https://api.freshservice.com/v2/#view_a_requester
+func (f *FreshServiceClient) GetRequesterDetail(ctx context.Context, userId string) (*RequesterDetailAPIData, annotations.Annotations, error) {
+	ctx = withOperation(ctx, "GetRequesterDetail")
+	requesterUrl, err := url.JoinPath(f.baseUrl, "requesters", userId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var res *RequesterDetailAPIData
+	_, annotation, err := f.doRequest(ctx, http.MethodGet, requesterUrl, &res, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return res, annotation, nil
+}
+
+// ListDepartments lists departments, or companies in MSP mode.
+// https://api.freshservice.com/v2/#view_all_departments
+func (f *FreshServiceClient) ListDepartments(ctx context.Context, opts PageOptions) (*DepartmentsAPIData, string, annotations.Annotations, error) {
+	return listPage[DepartmentsAPIData](withOperation(ctx, "ListDepartments"), f, []string{"departments"}, opts)
+}
+
+// UpdateAgentDepartments replaces the departments of an agent.
+// https://api.freshservice.com/v2/#update_an_agent
+func (f *FreshServiceClient) UpdateAgentDepartments(ctx context.Context, departmentIDs []int64, userId string) (annotations.Annotations, error) {
+	ctx = withOperation(ctx, "UpdateAgentDepartments")
+	agentsUrl, err := url.JoinPath(f.baseUrl, "agents", userId)
+	if err != nil {
+		return nil, err
+	}
+	body := &UpdateUserDepartments{DepartmentIDs: departmentIDs}
+	_, annos, err := f.doRequest(ctx, http.MethodPut, agentsUrl, nil, body)
+	if err != nil {
+		return nil, err
+	}
+	return annos, nil
+}
+
+// CreateRequester creates a requester and returns it.
+// https://api.freshservice.com/v2/#create_requester
+func (f *FreshServiceClient) CreateRequester(ctx context.Context, requester *CreateRequester) (*RequesterDetailAPIData, annotations.Annotations, error) {
+	ctx = withOperation(ctx, "CreateRequester")
+	requestersUrl, err := url.JoinPath(f.baseUrl, "requesters")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var res *RequesterDetailAPIData
+	_, annotation, err := f.doRequest(ctx, http.MethodPost, requestersUrl, &res, requester)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return res, annotation, nil
+}
+
+// UpdateRequesterDepartments replaces the departments of a requester.
+// https://api.freshservice.com/v2/#update_a_requester
+func (f *FreshServiceClient) UpdateRequesterDepartments(ctx context.Context, departmentIDs []int64, userId string) (annotations.Annotations, error) {
+	ctx = withOperation(ctx, "UpdateRequesterDepartments")
+	requesterUrl, err := url.JoinPath(f.baseUrl, "requesters", userId)
+	if err != nil {
+		return nil, err
+	}
+	body := &UpdateUserDepartments{DepartmentIDs: departmentIDs}
+	_, annos, err := f.doRequest(ctx, http.MethodPut, requesterUrl, nil, body)
+	if err != nil {
+		return nil, err
+	}
+	return annos, nil
+}
+
+// ListLocations lists locations.
+// https://api.freshservice.com/v2/#view_all_locations
+func (f *FreshServiceClient) ListLocations(ctx context.Context, opts PageOptions) (*LocationsAPIData, string, annotations.Annotations, error) {
+	return listPage[LocationsAPIData](withOperation(ctx, "ListLocations"), f, []string{"locations"}, opts)
+}
+
+// UpdateAgentLocation sets the location of an agent, or clears it when locationID is nil.
+// https://api.freshservice.com/v2/#update_an_agent
+func (f *FreshServiceClient) UpdateAgentLocation(ctx context.Context, locationID *int64, userId string) (annotations.Annotations, error) {
+	ctx = withOperation(ctx, "UpdateAgentLocation")
+	agentsUrl, err := url.JoinPath(f.baseUrl, "agents", userId)
+	if err != nil {
+		return nil, err
+	}
+	body := &UpdateUserLocation{LocationID: locationID}
+	_, annos, err := f.doRequest(ctx, http.MethodPut, agentsUrl, nil, body)
+	if err != nil {
+		return nil, err
+	}
+	return annos, nil
+}
+
+// UpdateRequesterLocation sets the location of a requester, or clears it when locationID is nil.
+// https://api.freshservice.com/v2/#update_a_requester
+func (f *FreshServiceClient) UpdateRequesterLocation(ctx context.Context, locationID *int64, userId string) (annotations.Annotations, error) {
+	ctx = withOperation(ctx, "UpdateRequesterLocation")
+	requesterUrl, err := url.JoinPath(f.baseUrl, "requesters", userId)
+	if err != nil {
+		return nil, err
+	}
+	body := &UpdateUserLocation{LocationID: locationID}
+	_, annos, err := f.doRequest(ctx, http.MethodPut, requesterUrl, nil, body)
+	if err != nil {
+		return nil, err
+	}
+	return annos, nil
+}
+
+// ListApplications lists the applications tracked by SaaS Management.
+// https://api.freshservice.com/v2/#list_all_software
+func (f *FreshServiceClient) ListApplications(ctx context.Context, opts PageOptions) (*ApplicationsAPIData, string, annotations.Annotations, error) {
+	return listPage[ApplicationsAPIData](withOperation(ctx, "ListApplications"), f, []string{"applications"}, opts)
+}
+
+// ListApplicationUsers lists the users of an application.
+// https://api.freshservice.com/v2/#list_all_software_users
+func (f *FreshServiceClient) ListApplicationUsers(ctx context.Context, applicationId string, opts PageOptions) (*ApplicationUsersAPIData, string, annotations.Annotations, error) {
+	return listPage[ApplicationUsersAPIData](withOperation(ctx, "ListApplicationUsers"), f, []string{"applications", applicationId, "users"}, opts)
+}
+
+// AddApplicationUsers adds users to an application.
+// https://api.freshservice.com/v2/#add_users_to_software
+func (f *FreshServiceClient) AddApplicationUsers(ctx context.Context, applicationId string, users []ApplicationUser) (annotations.Annotations, error) {
+	ctx = withOperation(ctx, "AddApplicationUsers")
+	usersUrl, err := url.JoinPath(f.baseUrl, "applications", applicationId, "users")
+	if err != nil {
+		return nil, err
+	}
+	body := &ApplicationUsersAPIData{ApplicationUsers: users}
+	_, annos, err := f.doRequest(ctx, http.MethodPost, usersUrl, nil, body)
+	if err != nil {
+		return nil, err
+	}
+	return annos, nil
+}
+
+// UpdateApplicationUsers assigns licenses to, or unassigns them from, users of an application.
+// https://api.freshservice.com/v2/#update_software_users
+func (f *FreshServiceClient) UpdateApplicationUsers(ctx context.Context, applicationId string, users []ApplicationUserLicense) (annotations.Annotations, error) {
+	ctx = withOperation(ctx, "UpdateApplicationUsers")
+	usersUrl, err := url.JoinPath(f.baseUrl, "applications", applicationId, "users")
+	if err != nil {
+		return nil, err
+	}
+	body := &UpdateApplicationUsers{ApplicationUsers: users}
+	_, annos, err := f.doRequest(ctx, http.MethodPut, usersUrl, nil, body)
+	if err != nil {
+		return nil, err
+	}
+	return annos, nil
+}
+
+// DeleteApplicationUsers removes users, by agent or requester ID, from an application.
+// https://api.freshservice.com/v2/#delete_users_from_software
+func (f *FreshServiceClient) DeleteApplicationUsers(ctx context.Context, applicationId string, userIds ...string) (annotations.Annotations, error) {
+	ctx = withOperation(ctx, "DeleteApplicationUsers")
+	usersUrl, err := url.JoinPath(f.baseUrl, "applications", applicationId, "users")
+	if err != nil {
+		return nil, err
+	}
+	_, annos, err := f.doRequest(ctx, http.MethodDelete, usersUrl, nil, nil, WithQueryParam("user_ids", strings.Join(userIds, ",")))
+	if err != nil {
+		return nil, err
+	}
+	return annos, nil
+}
+
+// ListAssetTypes lists asset types.
+// https://api.freshservice.com/v2/#list_all_asset_types
+func (f *FreshServiceClient) ListAssetTypes(ctx context.Context, opts PageOptions) (*AssetTypesAPIData, string, annotations.Annotations, error) {
+	return listPage[AssetTypesAPIData](withOperation(ctx, "ListAssetTypes"), f, []string{"asset_types"}, opts)
+}
+
+// ListAssets lists assets. Pass WithAssetTypeID to list a single asset type.
+// https://api.freshservice.com/v2/#list_all_assets
+func (f *FreshServiceClient) ListAssets(ctx context.Context, opts PageOptions, reqOpts ...ReqOpt) (*AssetsAPIData, string, annotations.Annotations, error) {
+	return listPage[AssetsAPIData](withOperation(ctx, "ListAssets"), f, []string{"assets"}, opts, reqOpts...)
+}
+
+// GetAsset. View an asset by display ID.
+// https://api.freshservice.com/v2/#view_an_asset
+func (f *FreshServiceClient) GetAsset(ctx context.Context, displayId string) (*AssetDetailAPIData, annotations.Annotations, error) {
+	ctx = withOperation(ctx, "GetAsset")
+	assetUrl, err := url.JoinPath(f.baseUrl, "assets", displayId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var res *AssetDetailAPIData
+	_, annotation, err := f.doRequest(ctx, http.MethodGet, assetUrl, &res, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return res, annotation, nil
+}
+
+// UpdateAssetUser sets the user of an asset, or clears it when userID is nil.
+// https://api.freshservice.com/v2/#update_an_asset
+func (f *FreshServiceClient) UpdateAssetUser(ctx context.Context, displayId string, userID *int64) (annotations.Annotations, error) {
+	return f.updateAsset(withOperation(ctx, "UpdateAssetUser"), displayId, &UpdateAssetUser{UserID: userID})
+}
+
+// UpdateAssetAgent sets the agent managing an asset, or clears it when agentID is nil.
+// https://api.freshservice.com/v2/#update_an_asset
+func (f *FreshServiceClient) UpdateAssetAgent(ctx context.Context, displayId string, agentID *int64) (annotations.Annotations, error) {
+	return f.updateAsset(withOperation(ctx, "UpdateAssetAgent"), displayId, &UpdateAssetAgent{AgentID: agentID})
+}
+
+// UpdateAssetGroup sets the agent group managing an asset, or clears it when groupID is nil.
+// https://api.freshservice.com/v2/#update_an_asset
+func (f *FreshServiceClient) UpdateAssetGroup(ctx context.Context, displayId string, groupID *int64) (annotations.Annotations, error) {
+	return f.updateAsset(withOperation(ctx, "UpdateAssetGroup"), displayId, &UpdateAssetGroup{GroupID: groupID})
+}
+
+func (f *FreshServiceClient) updateAsset(ctx context.Context, displayId string, body interface{}) (annotations.Annotations, error) {
+	assetUrl, err := url.JoinPath(f.baseUrl, "assets", displayId)
+	if err != nil {
+		return nil, err
+	}
+	_, annos, err := f.doRequest(ctx, http.MethodPut, assetUrl, nil, body)
+	if err != nil {
+		return nil, err
+	}
+	return annos, nil
+}
+
 func (f *FreshServiceClient) doRequest(
 	ctx context.Context,
 	method,
@@ -295,6 +576,58 @@ func (f *FreshServiceClient) doRequest(
 		o(urlAddress)
 	}
 
+	attempt := 0
+	if f.telemetry != nil {
+		var end func(*http.Response, int, error)
+		ctx, end = f.telemetry.start(ctx, operationName(ctx, method, f.baseUrl, urlAddress), method)
+		defer func() { end(resp, attempt, err) }()
+	}
+
+	for ; ; attempt++ {
+		err = f.rateGovernor.wait(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		resp, err = f.send(ctx, method, urlAddress, res, body)
+		throttled := f.rateGovernor.observe(resp)
+		if err != nil && throttled && attempt < maxRateLimitRetries {
+			ctxzap.Extract(ctx).Debug("freshservice-connector: rate limited, retrying request",
+				zap.String("url", urlAddress.String()),
+				zap.Int("attempt", attempt+1),
+			)
+			continue
+		}
+		break
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rateLimitData, err := extractRateLimitData(resp)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	annotation := annotations.Annotations{}
+	annotation.WithRateLimiting(rateLimitData)
+
+	return resp.Header, annotation, nil
+}
+
+// send performs a single attempt of a request.
+func (f *FreshServiceClient) send(
+	ctx context.Context,
+	method string,
+	urlAddress *url.URL,
+	res interface{},
+	body interface{},
+) (*http.Response, error) {
+	var (
+		resp *http.Response
+		err  error
+	)
 	req, err := f.httpClient.NewRequest(ctx,
 		method,
 		urlAddress,
@@ -304,7 +637,7 @@ func (f *FreshServiceClient) doRequest(
 		uhttp.WithJSONBody(body),
 	)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
 	switch method {
@@ -327,24 +660,17 @@ func (f *FreshServiceClient) doRequest(
 		}
 	}
 
-	if err != nil {
-		return nil, nil, err
+	if err != nil && resp != nil && (resp.StatusCode < 200 || resp.StatusCode >= 300) {
+		return resp, newAPIError(resp)
 	}
 
-	rateLimitData, err := extractRateLimitData(resp)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	annotation := annotations.Annotations{}
-	annotation.WithRateLimiting(rateLimitData)
-
-	return resp.Header, annotation, nil
+	return resp, err
 }
 
 // UpdateAgentRoles. Update an Agent.
 // https://api.freshservice.com/v2/#update_an_agent
 func (f *FreshServiceClient) UpdateAgentRoles(ctx context.Context, roleIDs []AgentRole, userId string) (annotations.Annotations, error) {
+	ctx = withOperation(ctx, "UpdateAgentRoles")
 	agentsUrl, err := url.JoinPath(f.baseUrl, "agents", userId)
 	if err != nil {
 		return nil, err
@@ -385,16 +711,17 @@ func extractRateLimitData(response *http.Response) (*v2.RateLimitDescription, er
 		}
 	}
 
+	// Retry-After is a number of seconds.
 	var resetAt *timestamppb.Timestamp
-	intervalMsPayload := response.Header.Get("Retry-After")
-	if intervalMsPayload != "" {
-		intervalMs, err := strconv.ParseInt(intervalMsPayload, 10, 64)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse retry-after: %w", err)
+	retryAfterPayload := response.Header.Get("Retry-After")
+	if retryAfterPayload != "" {
+		now := time.Now()
+		retryAfter, ok := parseRetryAfter(retryAfterPayload, now)
+		if !ok {
+			return nil, fmt.Errorf("failed to parse retry-after: %s", retryAfterPayload)
 		}
 
-		resetAtSeconds := time.Now().Add(time.Duration(intervalMs) * time.Millisecond).Unix()
-		resetAt = &timestamppb.Timestamp{Seconds: resetAtSeconds}
+		resetAt = timestamppb.New(now.Add(retryAfter))
 	}
 
 	return &v2.RateLimitDescription{
@@ -406,40 +733,29 @@ func extractRateLimitData(response *http.Response) (*v2.RateLimitDescription, er
 
 // https://api.freshservice.com/v2/#view_all_requester_group
 func (f *FreshServiceClient) ListRequesterGroups(ctx context.Context, opts PageOptions) (*RequesterGroupsAPIData, string, annotations.Annotations, error) {
-	requesterGroupsUrl, err := url.JoinPath(f.baseUrl, "requester_groups")
+	return listPage[RequesterGroupsAPIData](withOperation(ctx, "ListRequesterGroups"), f, []string{"requester_groups"}, opts)
+}
+
+// https://api.freshservice.com/v2/#view_a_requester_group
+func (f *FreshServiceClient) GetRequesterGroup(ctx context.Context, requesterGroupId string) (*RequesterGroupDetailAPIData, annotations.Annotations, error) {
+	ctx = withOperation(ctx, "GetRequesterGroup")
+	groupUrl, err := url.JoinPath(f.baseUrl, "requester_groups", requesterGroupId)
 	if err != nil {
-		return nil, "", nil, err
+		return nil, nil, err
 	}
-	var res *RequesterGroupsAPIData
-	nextPage, annotation, err := f.getListAPIData(ctx,
-		requesterGroupsUrl,
-		&res,
-		WithPage(opts.Page),
-		WithPageLimit(opts.PerPage),
-	)
+
+	var res *RequesterGroupDetailAPIData
+	_, annotation, err := f.doRequest(ctx, http.MethodGet, groupUrl, &res, nil)
 	if err != nil {
-		return nil, "", nil, err
+		return nil, nil, err
 	}
-	return res, nextPage, annotation, nil
+
+	return res, annotation, nil
 }
 
 // https://api.freshservice.com/v2/#list_members_of_requester_group
-func (f *FreshServiceClient) ListRequesterGroupMembers(ctx context.Context, requesterGroupId string, opts PageOptions) (*requesterGroupMembersAPIData, string, annotations.Annotations, error) {
-	requesterGroupMembersUrl, err := url.JoinPath(f.baseUrl, "requester_groups", requesterGroupId, "members")
-	if err != nil {
-		return nil, "", nil, err
-	}
-	var res *requesterGroupMembersAPIData
-	nextPage, annotation, err := f.getListAPIData(ctx,
-		requesterGroupMembersUrl,
-		&res,
-		WithPage(opts.Page),
-		WithPageLimit(opts.PerPage),
-	)
-	if err != nil {
-		return nil, "", nil, err
-	}
-	return res, nextPage, annotation, nil
+func (f *FreshServiceClient) ListRequesterGroupMembers(ctx context.Context, requesterGroupId string, opts PageOptions) (*RequesterGroupMembersAPIData, string, annotations.Annotations, error) {
+	return listPage[RequesterGroupMembersAPIData](withOperation(ctx, "ListRequesterGroupMembers"), f, []string{"requester_groups", requesterGroupId, "members"}, opts)
 }
 
 // AddRequesterToRequesterGroup. Add Requester to Requester Group.
@@ -449,6 +765,7 @@ func (f *FreshServiceClient) AddRequesterToRequesterGroup(
 	requesterGroupId string,
 	requesterId string,
 ) (annotations.Annotations, error) {
+	ctx = withOperation(ctx, "AddRequesterToRequesterGroup")
 	groupUrl, err := url.JoinPath(f.baseUrl, "requester_groups", requesterGroupId, "members", requesterId)
 	if err != nil {
 		return nil, err
@@ -467,6 +784,7 @@ func (f *FreshServiceClient) DeleteRequesterFromRequesterGroup(
 	requesterGroupId string,
 	requesterId string,
 ) (annotations.Annotations, error) {
+	ctx = withOperation(ctx, "DeleteRequesterFromRequesterGroup")
 	groupUrl, err := url.JoinPath(f.baseUrl, "requester_groups", requesterGroupId, "members", requesterId)
 	if err != nil {
 		return nil, err
@@ -478,7 +796,130 @@ func (f *FreshServiceClient) DeleteRequesterFromRequesterGroup(
 	return annotation, nil
 }
 
+// DeactivateAgent deactivates an agent, which keeps its tickets and can be reactivated.
+// https://api.freshservice.com/v2/#deactivate_an_agent
+func (f *FreshServiceClient) DeactivateAgent(ctx context.Context, agentId string) (annotations.Annotations, error) {
+	ctx = withOperation(ctx, "DeactivateAgent")
+	agentUrl, err := url.JoinPath(f.baseUrl, "agents", agentId)
+	if err != nil {
+		return nil, err
+	}
+	_, annotation, err := f.doRequest(ctx, http.MethodDelete, agentUrl, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return annotation, nil
+}
+
+// DeactivateRequester deactivates a requester, which keeps its tickets and can be reactivated.
+// https://api.freshservice.com/v2/#deactivate_a_requester
+func (f *FreshServiceClient) DeactivateRequester(ctx context.Context, requesterId string) (annotations.Annotations, error) {
+	ctx = withOperation(ctx, "DeactivateRequester")
+	requesterUrl, err := url.JoinPath(f.baseUrl, "requesters", requesterId)
+	if err != nil {
+		return nil, err
+	}
+	_, annotation, err := f.doRequest(ctx, http.MethodDelete, requesterUrl, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return annotation, nil
+}
+
+// ForgetRequester permanently deletes a requester and the tickets it requested. It can't be undone.
+// https://api.freshservice.com/v2/#forget_a_requester
+func (f *FreshServiceClient) ForgetRequester(ctx context.Context, requesterId string) (annotations.Annotations, error) {
+	ctx = withOperation(ctx, "ForgetRequester")
+	requesterUrl, err := url.JoinPath(f.baseUrl, "requesters", requesterId, "forget")
+	if err != nil {
+		return nil, err
+	}
+	_, annotation, err := f.doRequest(ctx, http.MethodDelete, requesterUrl, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return annotation, nil
+}
+
+// ReactivateAgent reactivates a deactivated agent.
+// https://api.freshservice.com/v2/#reactivate_an_agent
+func (f *FreshServiceClient) ReactivateAgent(ctx context.Context, agentId string) (annotations.Annotations, error) {
+	ctx = withOperation(ctx, "ReactivateAgent")
+	agentUrl, err := url.JoinPath(f.baseUrl, "agents", agentId, "reactivate")
+	if err != nil {
+		return nil, err
+	}
+	_, annotation, err := f.doRequest(ctx, http.MethodPut, agentUrl, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return annotation, nil
+}
+
+// ReactivateRequester reactivates a deactivated requester.
+// https://api.freshservice.com/v2/#reactivate_a_requester
+func (f *FreshServiceClient) ReactivateRequester(ctx context.Context, requesterId string) (annotations.Annotations, error) {
+	ctx = withOperation(ctx, "ReactivateRequester")
+	requesterUrl, err := url.JoinPath(f.baseUrl, "requesters", requesterId, "reactivate")
+	if err != nil {
+		return nil, err
+	}
+	_, annotation, err := f.doRequest(ctx, http.MethodPut, requesterUrl, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return annotation, nil
+}
+
+// ConvertRequesterToAgent converts a requester to an occasional agent with the same ID, keeping its tickets.
+// https://api.freshservice.com/v2/#convert_requester_to_agent
+func (f *FreshServiceClient) ConvertRequesterToAgent(ctx context.Context, requesterId string) (annotations.Annotations, error) {
+	ctx = withOperation(ctx, "ConvertRequesterToAgent")
+	requesterUrl, err := url.JoinPath(f.baseUrl, "requesters", requesterId, "convert_to_agent")
+	if err != nil {
+		return nil, err
+	}
+	_, annotation, err := f.doRequest(ctx, http.MethodPut, requesterUrl, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return annotation, nil
+}
+
+// ConvertAgentToRequester converts an agent to a requester with the same ID, keeping its tickets and releasing its
+// license.
+// https://api.freshservice.com/v2/#convert_agent_to_requester
+func (f *FreshServiceClient) ConvertAgentToRequester(ctx context.Context, agentId string) (annotations.Annotations, error) {
+	ctx = withOperation(ctx, "ConvertAgentToRequester")
+	agentUrl, err := url.JoinPath(f.baseUrl, "agents", agentId, "convert_to_requester")
+	if err != nil {
+		return nil, err
+	}
+	_, annotation, err := f.doRequest(ctx, http.MethodPut, agentUrl, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return annotation, nil
+}
+
+// UpdateAgentOccasional switches an agent between a full-time license and day passes.
+// https://api.freshservice.com/v2/#update_an_agent
+func (f *FreshServiceClient) UpdateAgentOccasional(ctx context.Context, agentId string, occasional bool) (annotations.Annotations, error) {
+	ctx = withOperation(ctx, "UpdateAgentOccasional")
+	agentUrl, err := url.JoinPath(f.baseUrl, "agents", agentId)
+	if err != nil {
+		return nil, err
+	}
+	body := &UpdateAgentOccasional{Occasional: occasional}
+	_, annotation, err := f.doRequest(ctx, http.MethodPut, agentUrl, nil, body)
+	if err != nil {
+		return nil, err
+	}
+	return annotation, nil
+}
+
 func (f *FreshServiceClient) GetTicket(ctx context.Context, ticketId string) (*TicketDetails, annotations.Annotations, error) {
+	ctx = withOperation(ctx, "GetTicket")
 	getTicketUrl, err := url.JoinPath(f.baseUrl, "tickets", ticketId)
 	if err != nil {
 		return nil, nil, err
@@ -491,22 +932,24 @@ func (f *FreshServiceClient) GetTicket(ctx context.Context, ticketId string) (*T
 	return res.Ticket, annos, nil
 }
 
-// TODO(lauren) this can take workspace_id as query param
-func (f *FreshServiceClient) GetTicketFields(ctx context.Context) (*TicketFieldsResponse, error) {
+// GetTicketFields lists the ticket form fields, of a single workspace when called with WithWorkspaceID.
+// https://api.freshservice.com/v2/#list_all_ticket_form_fields
+func (f *FreshServiceClient) GetTicketFields(ctx context.Context, reqOpts ...ReqOpt) (*TicketFieldsResponse, error) {
+	ctx = withOperation(ctx, "GetTicketFields")
 	ticketFormFieldsUrl, err := url.JoinPath(f.baseUrl, "ticket_form_fields")
 	if err != nil {
 		return nil, err
 	}
 	var res *TicketFieldsResponse
-	_, _, err = f.doRequest(ctx, http.MethodGet, ticketFormFieldsUrl, &res, nil)
+	_, _, err = f.doRequest(ctx, http.MethodGet, ticketFormFieldsUrl, &res, nil, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
 	return res, nil
 }
 
-func (f *FreshServiceClient) GetTicketStatuses(ctx context.Context) ([]*v2.TicketStatus, error) {
-	ticketFields, err := f.GetTicketFields(ctx)
+func (f *FreshServiceClient) GetTicketStatuses(ctx context.Context, reqOpts ...ReqOpt) ([]*v2.TicketStatus, error) {
+	ticketFields, err := f.GetTicketFields(ctx, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -526,6 +969,7 @@ func (f *FreshServiceClient) GetTicketStatuses(ctx context.Context) ([]*v2.Ticke
 }
 
 func (f *FreshServiceClient) GetServiceItem(ctx context.Context, serviceItemID string) (*ServiceItem, error) {
+	ctx = withOperation(ctx, "GetServiceItem")
 	serviceItemUrl, err := url.JoinPath(f.baseUrl, "service_catalog", "items", serviceItemID)
 	if err != nil {
 		return nil, err
@@ -539,37 +983,30 @@ func (f *FreshServiceClient) GetServiceItem(ctx context.Context, serviceItemID s
 	return res.ServiceItem, nil
 }
 
-// TODO(lauren) this can take workspace_id as query param
-// TODO(lauren) this can take category as query param
-func (f *FreshServiceClient) ListServiceCatalogItems(ctx context.Context, opts PageOptions) (*ServiceCatalogItemsListResponse, annotations.Annotations, string, error) {
-	ticketFormFieldsUrl, err := url.JoinPath(f.baseUrl, "service_catalog", "items")
+// ListServiceCatalogItems lists the service catalog items of the configured category,
+// of a single workspace when called with WithWorkspaceID.
+// https://api.freshservice.com/v2/#list_all_service_items
+func (f *FreshServiceClient) ListServiceCatalogItems(ctx context.Context, opts PageOptions, reqOpts ...ReqOpt) (*ServiceCatalogItemsListResponse, annotations.Annotations, string, error) {
+	ctx = withOperation(ctx, "ListServiceCatalogItems")
+	reqOpts = append(f.serviceCatalogItemFilters(), reqOpts...)
+	res, nextPage, annos, err := listPage[ServiceCatalogItemsListResponse](ctx, f, []string{"service_catalog", "items"}, opts, reqOpts...)
 	if err != nil {
 		return nil, nil, "", err
 	}
 
-	reqOpts := []ReqOpt{
-		WithPage(opts.Page),
-		WithPageLimit(opts.PerPage),
-	}
+	return res, annos, nextPage, nil
+}
 
+func (f *FreshServiceClient) serviceCatalogItemFilters() []ReqOpt {
+	var reqOpts []ReqOpt
 	if f.GetCategoryID() != "" {
 		reqOpts = append(reqOpts, WithQueryParam("category_id", f.GetCategoryID()))
 	}
-
-	var res *ServiceCatalogItemsListResponse
-	nextPage, annos, err := f.getListAPIData(ctx,
-		ticketFormFieldsUrl,
-		&res,
-		reqOpts...,
-	)
-	if err != nil {
-		return nil, nil, "", err
-	}
-
-	return res, annos, nextPage, nil
+	return reqOpts
 }
 
 func (f *FreshServiceClient) CreateServiceRequest(ctx context.Context, serviceCatalogItemID string, payload *ServiceRequestPayload) (*ServiceRequest, annotations.Annotations, error) {
+	ctx = withOperation(ctx, "CreateServiceRequest")
 	placeRequestUrl, err := url.JoinPath(f.baseUrl, "service_catalog", "items", serviceCatalogItemID, "place_request")
 	if err != nil {
 		return nil, nil, err
@@ -583,6 +1020,7 @@ func (f *FreshServiceClient) CreateServiceRequest(ctx context.Context, serviceCa
 }
 
 func (f *FreshServiceClient) UpdateTicket(ctx context.Context, ticketID string, payload *TicketUpdatePayload) (*TicketDetails, annotations.Annotations, error) {
+	ctx = withOperation(ctx, "UpdateTicket")
 	updateTicketUrl, err := url.JoinPath(f.baseUrl, "tickets", ticketID)
 	if err != nil {
 		return nil, nil, err
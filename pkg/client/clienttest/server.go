@@ -0,0 +1,1813 @@
+// Package clienttest provides an in-process fake of the Freshservice v2 API for offline tests.
+//
+// The fake implements the endpoints used by client.FreshServiceClient, paginates list endpoints with
+// `Link: rel="next"` headers, reports X-RateLimit headers and keeps memberships, roles and tickets in memory
+// so grant and revoke round trips can be asserted. Point a client at it through WithBaseURL, or use
+// Server.NewClient.
+package clienttest
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/conductorone/baton-freshservice/pkg/client"
+)
+
+const (
+	// APIKey is the API key accepted by the fake.
+	APIKey = "fake-api-key"
+	// Domain is the Freshservice domain reported by clients created through NewClient.
+	Domain = "fake"
+
+	// DefaultRateLimit is the per-minute request budget reported in X-RateLimit-Total.
+	DefaultRateLimit = 5000
+
+	defaultPerPage = 30
+	maxPerPage     = 100
+)
+
+// Server is a fake Freshservice API. It is safe for concurrent use.
+type Server struct {
+	*httptest.Server
+
+	mu sync.Mutex
+
+	workspaces           map[int64]*client.Workspace
+	agents               map[int64]*client.Agent
+	requesters           map[int64]*client.Requesters
+	groups               map[int64]*client.AgentGroup
+	roles                map[int64]*client.Roles
+	departments          map[int64]*client.Department
+	locations            map[int64]*client.Location
+	applications         map[int64]*client.Application
+	applicationUsers     map[int64][]client.ApplicationUser
+	applicationLicenses  map[int64][]client.ApplicationLicense
+	assetTypes           map[int64]*client.AssetType
+	assets               map[int64]*client.Asset
+	requesterGroups      map[int64]*client.RequesterGroup
+	requesterGroupMember map[int64][]int64
+	serviceItems         map[int64]*client.ServiceItem
+	tickets              map[int]*client.TicketDetails
+	ticketFields         []client.TicketField
+	currentAgentID       int64
+	nextTicketID         int
+
+	rateLimit   int
+	windowStart time.Time
+	used        int
+	throttle    int
+	requests    []string
+	held        map[string]chan struct{}
+}
+
+// NewServer starts a fake Freshservice API that is shut down when the test finishes.
+//
+// The uhttp response cache is disabled for the test, as it would otherwise serve stale GETs after the fake's
+// state changes.
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+	t.Setenv("BATON_DISABLE_HTTP_CACHE", "true")
+
+	s := &Server{
+		workspaces:           make(map[int64]*client.Workspace),
+		agents:               make(map[int64]*client.Agent),
+		requesters:           make(map[int64]*client.Requesters),
+		groups:               make(map[int64]*client.AgentGroup),
+		roles:                make(map[int64]*client.Roles),
+		departments:          make(map[int64]*client.Department),
+		locations:            make(map[int64]*client.Location),
+		applications:         make(map[int64]*client.Application),
+		applicationUsers:     make(map[int64][]client.ApplicationUser),
+		applicationLicenses:  make(map[int64][]client.ApplicationLicense),
+		assetTypes:           make(map[int64]*client.AssetType),
+		assets:               make(map[int64]*client.Asset),
+		requesterGroups:      make(map[int64]*client.RequesterGroup),
+		requesterGroupMember: make(map[int64][]int64),
+		serviceItems:         make(map[int64]*client.ServiceItem),
+		tickets:              make(map[int]*client.TicketDetails),
+		ticketFields:         defaultTicketFields(),
+		nextTicketID:         1,
+		rateLimit:            DefaultRateLimit,
+	}
+	s.Server = httptest.NewServer(s.handler())
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// NewClient returns a FreshServiceClient talking to the fake.
+func (s *Server) NewClient(t testing.TB) *client.FreshServiceClient {
+	t.Helper()
+	fs, err := client.New(context.Background(), client.NewClient(nil).
+		WithBearerToken(APIKey).
+		WithDomain(Domain).
+		WithBaseURL(s.URL))
+	if err != nil {
+		t.Fatalf("clienttest: creating client: %v", err)
+	}
+	return fs
+}
+
+// AddWorkspace stores a workspace.
+func (s *Server) AddWorkspace(workspace client.Workspace) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.workspaces[workspace.ID] = &workspace
+}
+
+// AddAgent stores an agent. The first agent added is the one returned for /agents/me.
+func (s *Server) AddAgent(agent client.Agent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.agents[agent.ID] = &agent
+	if s.currentAgentID == 0 {
+		s.currentAgentID = agent.ID
+	}
+}
+
+// Agent returns a copy of the stored agent.
+func (s *Server) Agent(id int64) (client.Agent, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	agent, ok := s.agents[id]
+	if !ok {
+		return client.Agent{}, false
+	}
+	return *agent, true
+}
+
+// AddRequester stores a requester.
+func (s *Server) AddRequester(requester client.Requesters) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requesters[requester.ID] = &requester
+}
+
+// Requester returns a copy of the stored requester.
+func (s *Server) Requester(id int64) (client.Requesters, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	requester, ok := s.requesters[id]
+	if !ok {
+		return client.Requesters{}, false
+	}
+	return *requester, true
+}
+
+// RemoveRequester deletes a requester, as forgetting one in Freshservice would.
+func (s *Server) RemoveRequester(id int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.requesters, id)
+}
+
+// AddGroup stores an agent group.
+func (s *Server) AddGroup(group client.AgentGroup) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	group.Members = slices.Clone(group.Members)
+	group.Observers = slices.Clone(group.Observers)
+	group.Leaders = slices.Clone(group.Leaders)
+	s.groups[group.ID] = &group
+}
+
+// Group returns a copy of the stored agent group.
+func (s *Server) Group(id int64) (client.AgentGroup, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	group, ok := s.groups[id]
+	if !ok {
+		return client.AgentGroup{}, false
+	}
+	return *group, true
+}
+
+// GroupMembers returns the agent IDs in an agent group.
+func (s *Server) GroupMembers(id int64) []int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	group, ok := s.groups[id]
+	if !ok {
+		return nil
+	}
+	return slices.Clone(group.Members)
+}
+
+// AddRole stores a role.
+func (s *Server) AddRole(role client.Roles) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.roles[role.ID] = &role
+}
+
+// AddDepartment stores a department.
+func (s *Server) AddDepartment(department client.Department) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.departments[department.ID] = &department
+}
+
+// AddLocation stores a location.
+func (s *Server) AddLocation(location client.Location) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.locations[location.ID] = &location
+}
+
+// AddApplication stores an application used by the given agents or requesters.
+func (s *Server) AddApplication(application client.Application, userIDs ...int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.applications[application.ID] = &application
+	s.applicationUsers[application.ID] = nil
+	for _, userID := range userIDs {
+		s.applicationUsers[application.ID] = append(s.applicationUsers[application.ID], client.ApplicationUser{UserID: userID})
+	}
+}
+
+// AddApplicationUser adds a user, with its license if any, to an application.
+func (s *Server) AddApplicationUser(id int64, user client.ApplicationUser) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.applicationUsers[id] = append(s.applicationUsers[id], user)
+}
+
+// ApplicationUser returns a copy of a user of an application.
+func (s *Server) ApplicationUser(id int64, userID int64) (client.ApplicationUser, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, user := range s.applicationUsers[id] {
+		if user.UserID == userID {
+			return user, true
+		}
+	}
+	return client.ApplicationUser{}, false
+}
+
+// AddApplicationLicense stores a license of an application. Its allocated count is computed from the users the
+// license is assigned to.
+func (s *Server) AddApplicationLicense(id int64, license client.ApplicationLicense) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.applicationLicenses[id] = append(s.applicationLicenses[id], license)
+}
+
+// ApplicationUsers returns the agent and requester IDs using an application.
+func (s *Server) ApplicationUsers(id int64) []int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var rv []int64
+	for _, user := range s.applicationUsers[id] {
+		rv = append(rv, user.UserID)
+	}
+	return rv
+}
+
+// AddAssetType stores an asset type.
+func (s *Server) AddAssetType(assetType client.AssetType) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.assetTypes[assetType.ID] = &assetType
+}
+
+// AddAsset stores an asset. Assets are looked up by display ID, as in Freshservice.
+func (s *Server) AddAsset(asset client.Asset) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.assets[asset.DisplayID] = &asset
+}
+
+// Asset returns a copy of the asset with the display ID.
+func (s *Server) Asset(displayID int64) (client.Asset, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	asset, ok := s.assets[displayID]
+	if !ok {
+		return client.Asset{}, false
+	}
+	return *asset, true
+}
+
+// AddRequesterGroup stores a requester group with the given requester members.
+func (s *Server) AddRequesterGroup(group client.RequesterGroup, members ...int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requesterGroups[group.ID] = &group
+	s.requesterGroupMember[group.ID] = slices.Clone(members)
+}
+
+// RequesterGroupMembers returns the requester IDs in a requester group.
+func (s *Server) RequesterGroupMembers(id int64) []int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.requesterGroupMember[id])
+}
+
+// AddServiceItem stores a service catalog item. Items are looked up by display ID, as in Freshservice.
+func (s *Server) AddServiceItem(item client.ServiceItem) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.serviceItems[item.DisplayID] = &item
+}
+
+// Ticket returns a copy of a ticket created through place_request.
+func (s *Server) Ticket(id int) (client.TicketDetails, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ticket, ok := s.tickets[id]
+	if !ok {
+		return client.TicketDetails{}, false
+	}
+	return *ticket, true
+}
+
+// SetRateLimit sets the per-minute request budget. Requests beyond it get a 429 until the minute is over.
+func (s *Server) SetRateLimit(limit int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rateLimit = limit
+}
+
+// Throttle answers the next n requests with 429 Too Many Requests and a zero Retry-After.
+func (s *Server) Throttle(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.throttle = n
+}
+
+// Hold keeps requests of "METHOD /path" from being served until release is called.
+func (s *Server) Hold(request string) (release func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.held == nil {
+		s.held = make(map[string]chan struct{})
+	}
+	ch := make(chan struct{})
+	s.held[request] = ch
+	return sync.OnceFunc(func() {
+		s.mu.Lock()
+		delete(s.held, request)
+		s.mu.Unlock()
+		close(ch)
+	})
+}
+
+// Requests returns the requests served so far as "METHOD /path?query".
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.requests)
+}
+
+func (s *Server) handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /workspaces", s.listWorkspaces)
+	mux.HandleFunc("GET /agents", s.listAgents)
+	mux.HandleFunc("POST /agents", s.createAgent)
+	mux.HandleFunc("GET /agents/{id}", s.getAgent)
+	mux.HandleFunc("PUT /agents/{id}", s.updateAgent)
+	mux.HandleFunc("DELETE /agents/{id}", s.deactivateAgent)
+	mux.HandleFunc("PUT /agents/{id}/reactivate", s.reactivateAgent)
+	mux.HandleFunc("PUT /agents/{id}/convert_to_requester", s.convertAgentToRequester)
+	mux.HandleFunc("GET /requesters", s.listRequesters)
+	mux.HandleFunc("POST /requesters", s.createRequester)
+	mux.HandleFunc("GET /requesters/{id}", s.getRequester)
+	mux.HandleFunc("PUT /requesters/{id}", s.updateRequester)
+	mux.HandleFunc("DELETE /requesters/{id}", s.deactivateRequester)
+	mux.HandleFunc("PUT /requesters/{id}/reactivate", s.reactivateRequester)
+	mux.HandleFunc("DELETE /requesters/{id}/forget", s.forgetRequester)
+	mux.HandleFunc("PUT /requesters/{id}/convert_to_agent", s.convertRequesterToAgent)
+	mux.HandleFunc("GET /departments", s.listDepartments)
+	mux.HandleFunc("GET /locations", s.listLocations)
+	mux.HandleFunc("GET /applications", s.listApplications)
+	mux.HandleFunc("GET /applications/{id}/users", s.listApplicationUsers)
+	mux.HandleFunc("POST /applications/{id}/users", s.addApplicationUsers)
+	mux.HandleFunc("PUT /applications/{id}/users", s.updateApplicationUsers)
+	mux.HandleFunc("GET /applications/{id}/licenses", s.listApplicationLicenses)
+	mux.HandleFunc("DELETE /applications/{id}/users", s.deleteApplicationUsers)
+	mux.HandleFunc("GET /asset_types", s.listAssetTypes)
+	mux.HandleFunc("GET /assets", s.listAssets)
+	mux.HandleFunc("GET /assets/{id}", s.getAsset)
+	mux.HandleFunc("PUT /assets/{id}", s.updateAsset)
+	mux.HandleFunc("GET /groups", s.listGroups)
+	mux.HandleFunc("GET /groups/{id}", s.getGroup)
+	mux.HandleFunc("PUT /groups/{id}", s.updateGroup)
+	mux.HandleFunc("GET /roles", s.listRoles)
+	mux.HandleFunc("GET /requester_groups", s.listRequesterGroups)
+	mux.HandleFunc("GET /requester_groups/{id}", s.getRequesterGroup)
+	mux.HandleFunc("GET /requester_groups/{id}/members", s.listRequesterGroupMembers)
+	mux.HandleFunc("POST /requester_groups/{id}/members/{requester}", s.addRequesterGroupMember)
+	mux.HandleFunc("DELETE /requester_groups/{id}/members/{requester}", s.deleteRequesterGroupMember)
+	mux.HandleFunc("GET /service_catalog/items", s.listServiceItems)
+	mux.HandleFunc("GET /service_catalog/items/{id}", s.getServiceItem)
+	mux.HandleFunc("POST /service_catalog/items/{id}/place_request", s.placeRequest)
+	mux.HandleFunc("GET /tickets/{id}", s.getTicket)
+	mux.HandleFunc("PUT /tickets/{id}", s.updateTicket)
+	mux.HandleFunc("GET /ticket_form_fields", s.listTicketFields)
+
+	return s.rateLimited(s.authenticated(mux))
+}
+
+func (s *Server) authenticated(next http.Handler) http.Handler {
+	want := "Basic " + base64.StdEncoding.EncodeToString([]byte(APIKey+":X"))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != want {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{
+				"code":    "invalid_credentials",
+				"message": "You have to be logged in to perform this action.",
+			})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) rateLimited(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests = append(s.requests, r.Method+" "+r.URL.RequestURI())
+		if held, ok := s.held[r.Method+" "+r.URL.Path]; ok {
+			s.mu.Unlock()
+			select {
+			case <-held:
+			case <-r.Context().Done():
+				return
+			}
+			s.mu.Lock()
+		}
+
+		now := time.Now()
+		if now.Sub(s.windowStart) >= time.Minute {
+			s.windowStart = now
+			s.used = 0
+		}
+
+		throttled := s.throttle > 0
+		retryAfter := 0
+		if throttled {
+			s.throttle--
+		} else if s.used >= s.rateLimit {
+			throttled = true
+			retryAfter = int(time.Until(s.windowStart.Add(time.Minute)).Seconds()) + 1
+		} else {
+			s.used++
+		}
+		w.Header().Set("X-RateLimit-Total", strconv.Itoa(s.rateLimit))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(s.rateLimit-s.used))
+		w.Header().Set("X-RateLimit-Used-CurrentRequest", "1")
+		s.mu.Unlock()
+
+		if throttled {
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			writeJSON(w, http.StatusTooManyRequests, map[string]string{
+				"message": "You have exceeded the limit of requests per minute",
+			})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) listWorkspaces(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	workspaces := sortedValues(s.workspaces)
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, client.WorkspacesAPIData{Workspaces: paginate(w, r, workspaces)})
+}
+
+func (s *Server) listAgents(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	agents := slices.DeleteFunc(sortedValues(s.agents), func(agent client.Agent) bool {
+		return !updatedSince(r, agent.UpdatedAt) || !agentMatches(r, &agent)
+	})
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, client.AgentsAPIData{Agents: paginate(w, r, agents)})
+}
+
+func (s *Server) getAgent(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := s.currentAgentID
+	if r.PathValue("id") != "me" {
+		var ok bool
+		if id, ok = pathID(w, r, "id"); !ok {
+			return
+		}
+	}
+	agent, ok := s.agents[id]
+	if !ok {
+		writeNotFound(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, client.AgentDetailAPIData{Agent: *agent})
+}
+
+func (s *Server) updateAgent(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	var body agentUpdate
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	agent, ok := s.agents[id]
+	if !ok {
+		writeNotFound(w)
+		return
+	}
+	if !s.validDepartments(w, body.DepartmentIDs) || !s.validLocation(w, body.LocationID) {
+		return
+	}
+	for _, role := range body.Roles {
+		if _, ok := s.roles[role.RoleID]; !ok {
+			writeValidationError(w, client.FieldError{
+				Field:   "role_id",
+				Message: fmt.Sprintf("There is no role matching the given role_id %d", role.RoleID),
+				Code:    "invalid_value",
+			})
+			return
+		}
+	}
+	if body.Roles != nil {
+		agent.Roles = slices.Clone(body.Roles)
+	}
+	if body.DepartmentIDs != nil {
+		agent.DepartmentIDs = slices.Clone(body.DepartmentIDs)
+	}
+	if body.LocationID.set {
+		agent.LocationID = body.LocationID.id
+	}
+	if body.Occasional != nil {
+		agent.Occasional = *body.Occasional
+	}
+	writeJSON(w, http.StatusOK, client.AgentDetailAPIData{Agent: *agent})
+}
+
+func (s *Server) createAgent(w http.ResponseWriter, r *http.Request) {
+	var body client.CreateAgent
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if body.FirstName == "" {
+		writeValidationError(w, client.FieldError{Field: "first_name", Message: "It should be a/an String", Code: "missing_field"})
+		return
+	}
+	if body.Email == "" {
+		writeValidationError(w, client.FieldError{Field: "email", Message: "It should be a valid email address", Code: "missing_field"})
+		return
+	}
+	if s.emailTaken(body.Email) {
+		writeValidationError(w, client.FieldError{
+			Field:   "email",
+			Message: fmt.Sprintf("It should be a unique value, %s is already in use", body.Email),
+			Code:    "duplicate_value",
+		})
+		return
+	}
+	location := optionalID{set: body.LocationID != nil, id: body.LocationID}
+	if !s.validDepartments(w, body.DepartmentIDs) || !s.validLocation(w, location) {
+		return
+	}
+	if body.ReportingManagerID != nil && !s.isUser(*body.ReportingManagerID) {
+		writeValidationError(w, client.FieldError{
+			Field:   "reporting_manager_id",
+			Message: fmt.Sprintf("There is no user matching the given reporting_manager_id %d", *body.ReportingManagerID),
+			Code:    "invalid_value",
+		})
+		return
+	}
+	if len(body.Roles) == 0 {
+		writeValidationError(w, client.FieldError{Field: "roles", Message: "It should be a/an Array", Code: "missing_field"})
+		return
+	}
+	for _, role := range body.Roles {
+		if _, ok := s.roles[role.RoleID]; !ok {
+			writeValidationError(w, client.FieldError{
+				Field:   "role_id",
+				Message: fmt.Sprintf("There is no role matching the given role_id %d", role.RoleID),
+				Code:    "invalid_value",
+			})
+			return
+		}
+		if !s.validGroups(w, "groups", role.Groups) {
+			return
+		}
+	}
+	if !s.validGroups(w, "member_of", body.MemberOf) {
+		return
+	}
+
+	agent := &client.Agent{
+		ID:                 s.nextUserID(),
+		Active:             true,
+		FirstName:          body.FirstName,
+		LastName:           body.LastName,
+		Email:              body.Email,
+		Occasional:         body.Occasional,
+		JobTitle:           body.JobTitle,
+		Roles:              slices.Clone(body.Roles),
+		DepartmentIDs:      slices.Clone(body.DepartmentIDs),
+		LocationID:         body.LocationID,
+		ReportingManagerID: body.ReportingManagerID,
+		UpdatedAt:          time.Now().UTC().Truncate(time.Second),
+	}
+	s.agents[agent.ID] = agent
+	for _, groupID := range body.MemberOf {
+		group := s.groups[groupID]
+		group.Members = s.updateMembers(group, append(slices.Clone(group.Members), agent.ID))
+	}
+	writeJSON(w, http.StatusCreated, client.AgentDetailAPIData{Agent: *agent})
+}
+
+// validGroups writes a validation error for field and returns false if any of ids isn't an agent group.
+// The caller must hold s.mu.
+func (s *Server) validGroups(w http.ResponseWriter, field string, ids []int64) bool {
+	for _, id := range ids {
+		if _, ok := s.groups[id]; !ok {
+			writeValidationError(w, client.FieldError{
+				Field:   field,
+				Message: fmt.Sprintf("There is no group matching the given group_id %d", id),
+				Code:    "invalid_value",
+			})
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Server) deactivateAgent(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	agent, ok := s.agents[id]
+	if !ok {
+		writeNotFound(w)
+		return
+	}
+	agent.Active = false
+	agent.UpdatedAt = time.Now().UTC().Truncate(time.Second)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) reactivateAgent(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	agent, ok := s.agents[id]
+	if !ok {
+		writeNotFound(w)
+		return
+	}
+	agent.Active = true
+	agent.UpdatedAt = time.Now().UTC().Truncate(time.Second)
+	writeJSON(w, http.StatusOK, client.AgentDetailAPIData{Agent: *agent})
+}
+
+// convertAgentToRequester turns an agent into a requester with the same ID. Like Freshservice, the agent leaves the
+// agent groups it was part of.
+func (s *Server) convertAgentToRequester(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	agent, ok := s.agents[id]
+	if !ok {
+		writeNotFound(w)
+		return
+	}
+	delete(s.agents, id)
+	for _, group := range s.groups {
+		group.Members = slices.DeleteFunc(group.Members, func(member int64) bool { return member == id })
+		group.Observers = slices.DeleteFunc(group.Observers, func(observer int64) bool { return observer == id })
+		group.Leaders = slices.DeleteFunc(group.Leaders, func(leader int64) bool { return leader == id })
+		if group.EscalateTo != nil && *group.EscalateTo == id {
+			group.EscalateTo = nil
+		}
+	}
+	requester := &client.Requesters{
+		ID:                 agent.ID,
+		Active:             agent.Active,
+		FirstName:          agent.FirstName,
+		LastName:           agent.LastName,
+		PrimaryEmail:       agent.Email,
+		JobTitle:           agent.JobTitle,
+		DepartmentIDs:      agent.DepartmentIDs,
+		LocationID:         agent.LocationID,
+		ReportingManagerID: agent.ReportingManagerID,
+		UpdatedAt:          time.Now().UTC().Truncate(time.Second),
+	}
+	s.requesters[id] = requester
+	writeJSON(w, http.StatusOK, client.RequesterDetailAPIData{Requester: *requester})
+}
+
+// agentUpdate is the subset of agent fields the fake can update. Fields left out of the request are nil or unset.
+type agentUpdate struct {
+	Roles         []client.AgentRole `json:"roles"`
+	DepartmentIDs []int64            `json:"department_ids"`
+	LocationID    optionalID         `json:"location_id"`
+	Occasional    *bool              `json:"occasional"`
+}
+
+// requesterUpdate is the subset of requester fields the fake can update. Fields left out of the request are nil
+// or unset.
+type requesterUpdate struct {
+	DepartmentIDs []int64    `json:"department_ids"`
+	LocationID    optionalID `json:"location_id"`
+}
+
+// optionalID is an ID field of an update, which is set to null to clear it.
+type optionalID struct {
+	set bool
+	id  *int64
+}
+
+func (o *optionalID) UnmarshalJSON(data []byte) error {
+	o.set = true
+	return json.Unmarshal(data, &o.id)
+}
+
+// validLocation writes a validation error and returns false if id is set to something that isn't a location.
+// The caller must hold s.mu.
+func (s *Server) validLocation(w http.ResponseWriter, id optionalID) bool {
+	if id.id == nil {
+		return true
+	}
+	if _, ok := s.locations[*id.id]; !ok {
+		writeValidationError(w, client.FieldError{
+			Field:   "location_id",
+			Message: fmt.Sprintf("There is no location matching the given location_id %d", *id.id),
+			Code:    "invalid_value",
+		})
+		return false
+	}
+	return true
+}
+
+// validDepartments writes a validation error and returns false if any of ids isn't a department.
+// The caller must hold s.mu.
+func (s *Server) validDepartments(w http.ResponseWriter, ids []int64) bool {
+	for _, id := range ids {
+		if _, ok := s.departments[id]; !ok {
+			writeValidationError(w, client.FieldError{
+				Field:   "department_ids",
+				Message: fmt.Sprintf("There is no department matching the given department_id %d", id),
+				Code:    "invalid_value",
+			})
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Server) listRequesters(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	requesters := slices.DeleteFunc(sortedValues(s.requesters), func(requester client.Requesters) bool {
+		return !updatedSince(r, requester.UpdatedAt)
+	})
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, client.RequestersAPIData{Requesters: paginate(w, r, requesters)})
+}
+
+func (s *Server) getRequester(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	requester, ok := s.requesters[id]
+	if !ok {
+		writeNotFound(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, client.RequesterDetailAPIData{Requester: *requester})
+}
+
+func (s *Server) createRequester(w http.ResponseWriter, r *http.Request) {
+	var body client.CreateRequester
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if body.FirstName == "" {
+		writeValidationError(w, client.FieldError{Field: "first_name", Message: "It should be a/an String", Code: "missing_field"})
+		return
+	}
+	if body.PrimaryEmail == "" {
+		writeValidationError(w, client.FieldError{Field: "primary_email", Message: "It should be a valid email address", Code: "missing_field"})
+		return
+	}
+	if s.emailTaken(body.PrimaryEmail) {
+		writeValidationError(w, client.FieldError{
+			Field:   "primary_email",
+			Message: fmt.Sprintf("It should be a unique value, %s is already in use", body.PrimaryEmail),
+			Code:    "duplicate_value",
+		})
+		return
+	}
+	location := optionalID{set: body.LocationID != nil, id: body.LocationID}
+	if !s.validDepartments(w, body.DepartmentIDs) || !s.validLocation(w, location) {
+		return
+	}
+	if body.ReportingManagerID != nil && !s.isUser(*body.ReportingManagerID) {
+		writeValidationError(w, client.FieldError{
+			Field:   "reporting_manager_id",
+			Message: fmt.Sprintf("There is no user matching the given reporting_manager_id %d", *body.ReportingManagerID),
+			Code:    "invalid_value",
+		})
+		return
+	}
+
+	requester := &client.Requesters{
+		ID:                 s.nextUserID(),
+		Active:             true,
+		FirstName:          body.FirstName,
+		LastName:           body.LastName,
+		PrimaryEmail:       body.PrimaryEmail,
+		JobTitle:           body.JobTitle,
+		DepartmentIDs:      slices.Clone(body.DepartmentIDs),
+		LocationID:         body.LocationID,
+		ReportingManagerID: body.ReportingManagerID,
+		UpdatedAt:          time.Now().UTC().Truncate(time.Second),
+	}
+	s.requesters[requester.ID] = requester
+	writeJSON(w, http.StatusCreated, client.RequesterDetailAPIData{Requester: *requester})
+}
+
+// nextUserID returns an ID no agent or requester has, as they share IDs. The caller must hold s.mu.
+func (s *Server) nextUserID() int64 {
+	var rv int64
+	for id := range s.agents {
+		rv = max(rv, id)
+	}
+	for id := range s.requesters {
+		rv = max(rv, id)
+	}
+	return rv + 1
+}
+
+// isUser reports whether id is an agent or a requester. The caller must hold s.mu.
+func (s *Server) isUser(id int64) bool {
+	_, isAgent := s.agents[id]
+	_, isRequester := s.requesters[id]
+	return isAgent || isRequester
+}
+
+// emailTaken reports whether an agent or a requester has the email. The caller must hold s.mu.
+func (s *Server) emailTaken(email string) bool {
+	for _, agent := range s.agents {
+		if strings.EqualFold(agent.Email, email) {
+			return true
+		}
+	}
+	for _, requester := range s.requesters {
+		if strings.EqualFold(requester.PrimaryEmail, email) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) deactivateRequester(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	requester, ok := s.requesters[id]
+	if !ok {
+		writeNotFound(w)
+		return
+	}
+	requester.Active = false
+	requester.UpdatedAt = time.Now().UTC().Truncate(time.Second)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) reactivateRequester(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	requester, ok := s.requesters[id]
+	if !ok {
+		writeNotFound(w)
+		return
+	}
+	requester.Active = true
+	requester.UpdatedAt = time.Now().UTC().Truncate(time.Second)
+	writeJSON(w, http.StatusOK, client.RequesterDetailAPIData{Requester: *requester})
+}
+
+// convertRequesterToAgent turns a requester into an occasional agent with the same ID.
+func (s *Server) convertRequesterToAgent(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	requester, ok := s.requesters[id]
+	if !ok {
+		writeNotFound(w)
+		return
+	}
+	delete(s.requesters, id)
+	agent := &client.Agent{
+		ID:                 requester.ID,
+		Active:             requester.Active,
+		FirstName:          requester.FirstName,
+		LastName:           requester.LastName,
+		Email:              requester.PrimaryEmail,
+		Occasional:         true,
+		JobTitle:           requester.JobTitle,
+		DepartmentIDs:      requester.DepartmentIDs,
+		LocationID:         requester.LocationID,
+		ReportingManagerID: requester.ReportingManagerID,
+		UpdatedAt:          time.Now().UTC().Truncate(time.Second),
+	}
+	s.agents[id] = agent
+	writeJSON(w, http.StatusOK, client.AgentDetailAPIData{Agent: *agent})
+}
+
+// forgetRequester deletes a requester along with its requester group memberships.
+func (s *Server) forgetRequester(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.requesters[id]; !ok {
+		writeNotFound(w)
+		return
+	}
+	delete(s.requesters, id)
+	for group, members := range s.requesterGroupMember {
+		s.requesterGroupMember[group] = slices.DeleteFunc(members, func(member int64) bool { return member == id })
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) updateRequester(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	var body requesterUpdate
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	requester, ok := s.requesters[id]
+	if !ok {
+		writeNotFound(w)
+		return
+	}
+	if !s.validDepartments(w, body.DepartmentIDs) || !s.validLocation(w, body.LocationID) {
+		return
+	}
+	if body.DepartmentIDs != nil {
+		requester.DepartmentIDs = slices.Clone(body.DepartmentIDs)
+	}
+	if body.LocationID.set {
+		requester.LocationID = body.LocationID.id
+	}
+	writeJSON(w, http.StatusOK, client.RequesterDetailAPIData{Requester: *requester})
+}
+
+func (s *Server) listDepartments(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	departments := sortedValues(s.departments)
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, client.DepartmentsAPIData{Departments: paginate(w, r, departments)})
+}
+
+func (s *Server) listLocations(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	locations := sortedValues(s.locations)
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, client.LocationsAPIData{Locations: paginate(w, r, locations)})
+}
+
+func (s *Server) listApplications(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	applications := sortedValues(s.applications)
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, client.ApplicationsAPIData{Applications: paginate(w, r, applications)})
+}
+
+func (s *Server) listApplicationUsers(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	if _, ok := s.applications[id]; !ok {
+		s.mu.Unlock()
+		writeNotFound(w)
+		return
+	}
+	users := slices.Clone(s.applicationUsers[id])
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, client.ApplicationUsersAPIData{ApplicationUsers: paginate(w, r, users)})
+}
+
+func (s *Server) addApplicationUsers(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	var body client.ApplicationUsersAPIData
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.applications[id]; !ok {
+		writeNotFound(w)
+		return
+	}
+	for _, user := range body.ApplicationUsers {
+		if !s.validApplicationUser(w, id, user.UserID, user.LicenseID) {
+			return
+		}
+	}
+	for _, user := range body.ApplicationUsers {
+		if !slices.ContainsFunc(s.applicationUsers[id], func(u client.ApplicationUser) bool { return u.UserID == user.UserID }) {
+			s.applicationUsers[id] = append(s.applicationUsers[id], user)
+		}
+	}
+	writeJSON(w, http.StatusOK, client.ApplicationUsersAPIData{ApplicationUsers: s.applicationUsers[id]})
+}
+
+func (s *Server) updateApplicationUsers(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	var body client.UpdateApplicationUsers
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.applications[id]; !ok {
+		writeNotFound(w)
+		return
+	}
+	for _, update := range body.ApplicationUsers {
+		if !s.validApplicationUser(w, id, update.UserID, update.LicenseID) {
+			return
+		}
+		idx := slices.IndexFunc(s.applicationUsers[id], func(u client.ApplicationUser) bool { return u.UserID == update.UserID })
+		if idx < 0 {
+			writeNotFound(w)
+			return
+		}
+		s.applicationUsers[id][idx].LicenseID = update.LicenseID
+	}
+	writeJSON(w, http.StatusOK, client.ApplicationUsersAPIData{ApplicationUsers: s.applicationUsers[id]})
+}
+
+// validApplicationUser writes a validation error and returns false if userID isn't an agent or a requester, or
+// licenseID is set to something that isn't a license of the application. The caller must hold s.mu.
+func (s *Server) validApplicationUser(w http.ResponseWriter, id int64, userID int64, licenseID *int64) bool {
+	_, isAgent := s.agents[userID]
+	_, isRequester := s.requesters[userID]
+	if !isAgent && !isRequester {
+		writeValidationError(w, client.FieldError{
+			Field:   "user_id",
+			Message: fmt.Sprintf("There is no user matching the given user_id %d", userID),
+			Code:    "invalid_value",
+		})
+		return false
+	}
+	if licenseID != nil && !slices.ContainsFunc(s.applicationLicenses[id], func(l client.ApplicationLicense) bool { return l.ID == *licenseID }) {
+		writeValidationError(w, client.FieldError{
+			Field:   "license_id",
+			Message: fmt.Sprintf("There is no license matching the given license_id %d", *licenseID),
+			Code:    "invalid_value",
+		})
+		return false
+	}
+	return true
+}
+
+func (s *Server) listApplicationLicenses(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	if _, ok := s.applications[id]; !ok {
+		s.mu.Unlock()
+		writeNotFound(w)
+		return
+	}
+	licenses := slices.Clone(s.applicationLicenses[id])
+	for i := range licenses {
+		licenses[i].AllocatedCount = 0
+		for _, user := range s.applicationUsers[id] {
+			if user.LicenseID != nil && *user.LicenseID == licenses[i].ID {
+				licenses[i].AllocatedCount++
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, client.ApplicationLicensesAPIData{Licenses: paginate(w, r, licenses)})
+}
+
+func (s *Server) deleteApplicationUsers(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	var userIDs []int64
+	for _, userID := range strings.Split(r.URL.Query().Get("user_ids"), ",") {
+		parsed, err := strconv.ParseInt(userID, 10, 64)
+		if err != nil {
+			writeValidationError(w, client.FieldError{Field: "user_ids", Message: "It should be a list of user IDs", Code: "invalid_value"})
+			return
+		}
+		userIDs = append(userIDs, parsed)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.applications[id]; !ok {
+		writeNotFound(w)
+		return
+	}
+	s.applicationUsers[id] = slices.DeleteFunc(s.applicationUsers[id], func(u client.ApplicationUser) bool {
+		return slices.Contains(userIDs, u.UserID)
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) listAssetTypes(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	assetTypes := sortedValues(s.assetTypes)
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, client.AssetTypesAPIData{AssetTypes: paginate(w, r, assetTypes)})
+}
+
+// listAssets supports the asset_type_id filter only, given as filter="asset_type_id:ID".
+func (s *Server) listAssets(w http.ResponseWriter, r *http.Request) {
+	filter := r.URL.Query().Get("filter")
+	assetTypeID := strings.TrimSuffix(strings.TrimPrefix(filter, `"asset_type_id:`), `"`)
+	if filter != "" && assetTypeID == filter {
+		writeValidationError(w, client.FieldError{Field: "filter", Message: "Unsupported filter", Code: "invalid_value"})
+		return
+	}
+
+	s.mu.Lock()
+	assets := slices.DeleteFunc(sortedValues(s.assets), func(asset client.Asset) bool {
+		return filter != "" && strconv.FormatInt(asset.AssetTypeID, 10) != assetTypeID
+	})
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, client.AssetsAPIData{Assets: paginate(w, r, assets)})
+}
+
+func (s *Server) getAsset(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	asset, ok := s.assets[id]
+	if !ok {
+		writeNotFound(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, client.AssetDetailAPIData{Asset: *asset})
+}
+
+// assetUpdate is the subset of asset fields the fake can update. Fields left out of the request are unset.
+type assetUpdate struct {
+	UserID  optionalID `json:"user_id"`
+	AgentID optionalID `json:"agent_id"`
+	GroupID optionalID `json:"group_id"`
+}
+
+func (s *Server) updateAsset(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	var body assetUpdate
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	asset, ok := s.assets[id]
+	if !ok {
+		writeNotFound(w)
+		return
+	}
+	if body.UserID.id != nil {
+		_, isAgent := s.agents[*body.UserID.id]
+		_, isRequester := s.requesters[*body.UserID.id]
+		if !isAgent && !isRequester {
+			writeValidationError(w, client.FieldError{Field: "user_id", Message: "Invalid user", Code: "invalid_value"})
+			return
+		}
+	}
+	if body.AgentID.id != nil {
+		if _, ok := s.agents[*body.AgentID.id]; !ok {
+			writeValidationError(w, client.FieldError{Field: "agent_id", Message: "Invalid agent", Code: "invalid_value"})
+			return
+		}
+	}
+	if body.GroupID.id != nil {
+		if _, ok := s.groups[*body.GroupID.id]; !ok {
+			writeValidationError(w, client.FieldError{Field: "group_id", Message: "Invalid group", Code: "invalid_value"})
+			return
+		}
+	}
+	if body.UserID.set {
+		asset.UserID = body.UserID.id
+	}
+	if body.AgentID.set {
+		asset.AgentID = body.AgentID.id
+	}
+	if body.GroupID.set {
+		asset.GroupID = body.GroupID.id
+	}
+	writeJSON(w, http.StatusOK, client.AssetDetailAPIData{Asset: *asset})
+}
+
+func (s *Server) listGroups(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	groups := slices.DeleteFunc(sortedValues(s.groups), func(group client.AgentGroup) bool {
+		return !inWorkspace(r, group.WorkspaceID)
+	})
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, client.AgentGroupsAPIData{Groups: paginate(w, r, groups)})
+}
+
+func (s *Server) getGroup(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	group, ok := s.groups[id]
+	if !ok {
+		writeNotFound(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, client.AgentGroupDetailAPIData{Group: *group})
+}
+
+// groupUpdate is the subset of agent group fields the fake can update. Fields left out of the request are nil or
+// unset.
+type groupUpdate struct {
+	Members    *[]int64   `json:"members"`
+	Observers  *[]int64   `json:"observers"`
+	Leaders    *[]int64   `json:"leaders"`
+	EscalateTo optionalID `json:"escalate_to"`
+}
+
+func (s *Server) updateGroup(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	var body groupUpdate
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	group, ok := s.groups[id]
+	if !ok {
+		writeNotFound(w)
+		return
+	}
+	fields := map[string]*[]int64{"members": body.Members, "observers": body.Observers, "leaders": body.Leaders}
+	if body.EscalateTo.id != nil {
+		fields["escalate_to"] = &[]int64{*body.EscalateTo.id}
+	}
+	for field, agents := range fields {
+		if agents == nil {
+			continue
+		}
+		for _, agent := range *agents {
+			if _, ok := s.agents[agent]; !ok {
+				writeValidationError(w, client.FieldError{
+					Field:   field,
+					Message: fmt.Sprintf("There are no agents matching the given ids %d", agent),
+					Code:    "invalid_value",
+				})
+				return
+			}
+		}
+	}
+	if body.Members != nil {
+		group.Members = s.updateMembers(group, *body.Members)
+	}
+	if body.Observers != nil {
+		group.Observers = slices.Clone(*body.Observers)
+	}
+	if body.Leaders != nil {
+		group.Leaders = slices.Clone(*body.Leaders)
+	}
+	if body.EscalateTo.set {
+		group.EscalateTo = body.EscalateTo.id
+	}
+	writeJSON(w, http.StatusOK, client.AgentGroupDetailAPIData{Group: *group})
+}
+
+// updateMembers returns the members of group after replacing them with members. Like Freshservice, agents added to
+// an approval-required group are pending approval instead of becoming members, and stay pending until a group leader
+// approves or rejects them. The caller must hold s.mu.
+func (s *Server) updateMembers(group *client.AgentGroup, members []int64) []int64 {
+	if !group.ApprovalRequired {
+		return slices.Clone(members)
+	}
+
+	var rv []int64
+	for _, member := range members {
+		if slices.Contains(group.Members, member) {
+			rv = append(rv, member)
+			continue
+		}
+		if agent := s.agents[member]; !slices.Contains(agent.MemberOfPendingApproval, group.ID) {
+			agent.MemberOfPendingApproval = append(agent.MemberOfPendingApproval, group.ID)
+		}
+	}
+	return rv
+}
+
+// ApproveMembership approves the pending membership of an agent in an approval-required group.
+func (s *Server) ApproveMembership(groupID int64, agentID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	agent, ok := s.agents[agentID]
+	group, found := s.groups[groupID]
+	if !ok || !found || !slices.Contains(agent.MemberOfPendingApproval, groupID) {
+		return
+	}
+	agent.MemberOfPendingApproval = slices.DeleteFunc(agent.MemberOfPendingApproval, func(id int64) bool { return id == groupID })
+	group.Members = append(group.Members, agentID)
+}
+
+// RejectMembership rejects the pending membership of an agent in an approval-required group.
+func (s *Server) RejectMembership(groupID int64, agentID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if agent, ok := s.agents[agentID]; ok {
+		agent.MemberOfPendingApproval = slices.DeleteFunc(agent.MemberOfPendingApproval, func(id int64) bool { return id == groupID })
+	}
+}
+
+func (s *Server) listRoles(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	roles := sortedValues(s.roles)
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, client.RolesAPIData{Roles: paginate(w, r, roles)})
+}
+
+func (s *Server) listRequesterGroups(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	groups := sortedValues(s.requesterGroups)
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, client.RequesterGroupsAPIData{RequesterGroups: paginate(w, r, groups)})
+}
+
+func (s *Server) getRequesterGroup(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	group, ok := s.requesterGroups[id]
+	if !ok {
+		writeNotFound(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, client.RequesterGroupDetailAPIData{RequesterGroup: *group})
+}
+
+func (s *Server) listRequesterGroupMembers(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	if _, ok := s.requesterGroups[id]; !ok {
+		s.mu.Unlock()
+		writeNotFound(w)
+		return
+	}
+	members := make([]client.RequesterGroupMember, 0, len(s.requesterGroupMember[id]))
+	for _, requesterID := range s.requesterGroupMember[id] {
+		member := client.RequesterGroupMember{ID: int(requesterID)}
+		if requester, ok := s.requesters[requesterID]; ok {
+			member.FirstName = requester.FirstName
+			member.LastName = requester.LastName
+			member.Email = requester.PrimaryEmail
+		}
+		members = append(members, member)
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, client.RequesterGroupMembersAPIData{Requesters: paginate(w, r, members)})
+}
+
+func (s *Server) addRequesterGroupMember(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	requesterID, ok := pathID(w, r, "requester")
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	group, ok := s.requesterGroups[id]
+	if !ok {
+		writeNotFound(w)
+		return
+	}
+	if _, ok := s.requesters[requesterID]; !ok {
+		writeNotFound(w)
+		return
+	}
+	if !manualRequesterGroup(w, group) {
+		return
+	}
+	if !slices.Contains(s.requesterGroupMember[id], requesterID) {
+		s.requesterGroupMember[id] = append(s.requesterGroupMember[id], requesterID)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) deleteRequesterGroupMember(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	requesterID, ok := pathID(w, r, "requester")
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	members := s.requesterGroupMember[id]
+	idx := slices.Index(members, requesterID)
+	group, ok := s.requesterGroups[id]
+	if !ok || idx < 0 {
+		writeNotFound(w)
+		return
+	}
+	if !manualRequesterGroup(w, group) {
+		return
+	}
+	s.requesterGroupMember[id] = slices.Delete(members, idx, idx+1)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// manualRequesterGroup writes a validation error and returns false when the members of the group are managed by its
+// rules.
+func manualRequesterGroup(w http.ResponseWriter, group *client.RequesterGroup) bool {
+	if group.Type != client.RequesterGroupTypeRuleBased {
+		return true
+	}
+	writeValidationError(w, client.FieldError{
+		Field:   "type",
+		Message: "Members can't be added to or removed from a rule based requester group",
+		Code:    "invalid_value",
+	})
+	return false
+}
+
+func (s *Server) listServiceItems(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	items := make([]*client.ServiceItem, 0, len(s.serviceItems))
+	categoryID := r.URL.Query().Get("category_id")
+	for _, item := range sortedValues(s.serviceItems) {
+		if categoryID != "" && strconv.FormatInt(item.CategoryID, 10) != categoryID {
+			continue
+		}
+		if !inWorkspace(r, int64(item.WorkspaceID)) {
+			continue
+		}
+		itemCopy := item
+		items = append(items, &itemCopy)
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, client.ServiceCatalogItemsListResponse{ServiceItems: paginate(w, r, items)})
+}
+
+func (s *Server) getServiceItem(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	item, ok := s.serviceItems[id]
+	if !ok {
+		writeNotFound(w)
+		return
+	}
+	itemCopy := *item
+	writeJSON(w, http.StatusOK, client.ServiceCatalogItemResponse{ServiceItem: &itemCopy})
+}
+
+func (s *Server) placeRequest(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	var body client.ServiceRequestPayload
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	item, ok := s.serviceItems[id]
+	if !ok {
+		writeNotFound(w)
+		return
+	}
+	if body.Email == "" {
+		writeValidationError(w, client.FieldError{Field: "email", Message: "It should be a valid email address", Code: "missing_field"})
+		return
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	ticket := &client.TicketDetails{
+		ID:                 s.nextTicketID,
+		Type:               "Service Request",
+		Status:             2,
+		Subject:            fmt.Sprintf("Request for : %s", item.Name),
+		WorkspaceID:        item.WorkspaceID,
+		CustomFields:       body.CustomFields,
+		CreatedAt:          now,
+		UpdatedAt:          now,
+		ApprovalStatusName: "Not Requested",
+	}
+	s.nextTicketID++
+	s.tickets[ticket.ID] = ticket
+
+	writeJSON(w, http.StatusCreated, client.ServiceRequestResponse{
+		ServiceRequest: &client.ServiceRequest{
+			ApprovalStatusName: ticket.ApprovalStatusName,
+			TicketDetails:      *ticket,
+		},
+	})
+}
+
+func (s *Server) getTicket(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ticket, ok := s.tickets[int(id)]
+	if !ok {
+		writeNotFound(w)
+		return
+	}
+	ticketCopy := *ticket
+	writeJSON(w, http.StatusOK, client.TicketResponse{Ticket: &ticketCopy})
+}
+
+func (s *Server) updateTicket(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	var body client.TicketUpdatePayload
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ticket, ok := s.tickets[int(id)]
+	if !ok {
+		writeNotFound(w)
+		return
+	}
+	if body.Subject != "" {
+		ticket.Subject = body.Subject
+	}
+	if body.Description != "" {
+		ticket.Description = body.Description
+		ticket.DescriptionText = body.Description
+	}
+	if body.Tags != nil {
+		ticket.Tags = slices.Clone(body.Tags)
+	}
+	ticket.UpdatedAt = time.Now().UTC().Truncate(time.Second)
+
+	ticketCopy := *ticket
+	writeJSON(w, http.StatusOK, client.TicketResponse{Ticket: &ticketCopy})
+}
+
+func (s *Server) listTicketFields(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeJSON(w, http.StatusOK, client.TicketFieldsResponse{TicketFields: slices.Clone(s.ticketFields)})
+}
+
+func defaultTicketFields() []client.TicketField {
+	return []client.TicketField{
+		{
+			ID:           1,
+			Name:         "status",
+			Label:        "Status",
+			FieldType:    "default_status",
+			Required:     true,
+			DefaultField: true,
+			Choices: []client.Choice{
+				{ID: 2, Value: "Open"},
+				{ID: 3, Value: "Pending"},
+				{ID: 4, Value: "Resolved"},
+				{ID: 5, Value: "Closed"},
+			},
+		},
+	}
+}
+
+// inWorkspace reports whether a record of workspaceID matches the request's workspace_id filter, if any.
+func inWorkspace(r *http.Request, workspaceID int64) bool {
+	filter := r.URL.Query().Get("workspace_id")
+	return filter == "" || filter == strconv.FormatInt(workspaceID, 10)
+}
+
+// agentMatches reports whether an agent matches the request's active and state filters. Like Freshservice, only
+// active agents are listed unless active=false is given.
+func agentMatches(r *http.Request, agent *client.Agent) bool {
+	query := r.URL.Query()
+	if agent.Active != (query.Get("active") != "false") {
+		return false
+	}
+	switch query.Get("state") {
+	case "fulltime":
+		return !agent.Occasional
+	case "occasional":
+		return agent.Occasional
+	}
+	return true
+}
+
+// updatedSince reports whether a record updated at updatedAt matches the request's updated_since filter, if any.
+func updatedSince(r *http.Request, updatedAt time.Time) bool {
+	since, err := time.Parse(time.RFC3339, r.URL.Query().Get("updated_since"))
+	return err != nil || !updatedAt.Before(since)
+}
+
+// paginate returns the requested page of items and sets the Link header when there is a next page.
+func paginate[T any](w http.ResponseWriter, r *http.Request, items []T) []T {
+	query := r.URL.Query()
+	perPage, err := strconv.Atoi(query.Get("per_page"))
+	if err != nil || perPage <= 0 {
+		perPage = defaultPerPage
+	}
+	perPage = min(perPage, maxPerPage)
+	page, err := strconv.Atoi(query.Get("page"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+
+	start := min((page-1)*perPage, len(items))
+	end := min(start+perPage, len(items))
+	if end < len(items) {
+		query.Set("page", strconv.Itoa(page+1))
+		query.Set("per_page", strconv.Itoa(perPage))
+		next := url.URL{Scheme: "http", Host: r.Host, Path: r.URL.Path, RawQuery: query.Encode()}
+		w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, next.String()))
+	}
+
+	return items[start:end]
+}
+
+func sortedValues[K int64 | int, V any](m map[K]*V) []V {
+	keys := slices.Sorted(maps.Keys(m))
+	values := make([]V, 0, len(keys))
+	for _, k := range keys {
+		values = append(values, *m[k])
+	}
+	return values
+}
+
+func pathID(w http.ResponseWriter, r *http.Request, name string) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue(name), 10, 64)
+	if err != nil {
+		writeNotFound(w)
+		return 0, false
+	}
+	return id, true
+}
+
+func decodeBody(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"code":    "invalid_json",
+			"message": fmt.Sprintf("Request body has invalid json format: %v", err),
+		})
+		return false
+	}
+	return true
+}
+
+func writeNotFound(w http.ResponseWriter) {
+	writeJSON(w, http.StatusNotFound, map[string]string{"message": "Record Not Found"})
+}
+
+func writeValidationError(w http.ResponseWriter, errs ...client.FieldError) {
+	writeJSON(w, http.StatusBadRequest, map[string]any{
+		"description": "Validation failed",
+		"errors":      errs,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
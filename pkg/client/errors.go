@@ -0,0 +1,169 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/uhttp"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// errorResponse is the body Freshservice returns for failed requests.
+// https://api.freshservice.com/v2/#error
+type errorResponse struct {
+	Description    string       `json:"description"`
+	MessageContent string       `json:"message"`
+	Code           string       `json:"code"`
+	Errors         []FieldError `json:"errors"`
+}
+
+func (er *errorResponse) Message() string {
+	return fmt.Sprintf("Error: %s", er.MessageContent)
+}
+
+// FieldError is a single validation failure of a Freshservice request.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+	Code    string `json:"code"`
+}
+
+// APIError is a failed Freshservice API request. It implements GRPCStatus, so status.Code and
+// status.FromError report the gRPC code matching the HTTP status.
+type APIError struct {
+	StatusCode  int
+	Description string
+	// Code is the error code of errors that are not about a particular field, e.g. "access_denied".
+	Code   string
+	Errors []FieldError
+	// RetryAfter is set on 429 responses.
+	RetryAfter time.Duration
+	rateLimit  *v2.RateLimitDescription
+}
+
+func (e *APIError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "freshservice-connector: request failed with status %d", e.StatusCode)
+	if e.Description != "" {
+		fmt.Fprintf(&sb, ": %s", e.Description)
+	}
+	if e.Code != "" {
+		fmt.Fprintf(&sb, " (%s)", e.Code)
+	}
+	for _, fe := range e.Errors {
+		fmt.Fprintf(&sb, "; %s: %s", fe.Field, fe.Message)
+		if fe.Code != "" {
+			fmt.Fprintf(&sb, " (%s)", fe.Code)
+		}
+	}
+	return sb.String()
+}
+
+// GRPCCode maps the HTTP status of the failed request to a gRPC code.
+func (e *APIError) GRPCCode() codes.Code {
+	switch e.StatusCode {
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusTooManyRequests:
+		return codes.Unavailable
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	}
+	return uhttp.GrpcCodeFromHTTPStatus(e.StatusCode)
+}
+
+// GRPCStatus returns the gRPC status of the error, carrying the per-field validation errors as
+// BadRequest details and, on 429, the rate limit description used by the SDK to back off.
+func (e *APIError) GRPCStatus() *status.Status {
+	st := status.New(e.GRPCCode(), e.Error())
+
+	var details []protoadapt.MessageV1
+	if len(e.Errors) > 0 {
+		violations := make([]*errdetails.BadRequest_FieldViolation, 0, len(e.Errors))
+		for _, fe := range e.Errors {
+			violations = append(violations, &errdetails.BadRequest_FieldViolation{
+				Field:       fe.Field,
+				Description: fe.Message,
+				Reason:      fe.Code,
+			})
+		}
+		details = append(details, &errdetails.BadRequest{FieldViolations: violations})
+	}
+	if e.rateLimit != nil {
+		details = append(details, e.rateLimit)
+	}
+	if len(details) == 0 {
+		return st
+	}
+
+	withDetails, err := st.WithDetails(details...)
+	if err != nil {
+		return st
+	}
+	return withDetails
+}
+
+// HasStatus reports whether err is an APIError with the given HTTP status.
+func HasStatus(err error, statusCode int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == statusCode
+}
+
+// IsNotFound reports whether err is a 404 from Freshservice.
+func IsNotFound(err error) bool {
+	return HasStatus(err, http.StatusNotFound)
+}
+
+// newAPIError builds an APIError from a failed response. The body has already been buffered by uhttp.
+func newAPIError(resp *http.Response) *APIError {
+	apiErr := &APIError{StatusCode: resp.StatusCode}
+
+	var errRes errorResponse
+	body, err := io.ReadAll(resp.Body)
+	if err == nil && len(body) > 0 && json.Unmarshal(body, &errRes) == nil {
+		apiErr.Description = errRes.Description
+		if apiErr.Description == "" {
+			apiErr.Description = errRes.MessageContent
+		}
+		apiErr.Code = errRes.Code
+		apiErr.Errors = errRes.Errors
+	}
+	if apiErr.Description == "" {
+		apiErr.Description = http.StatusText(resp.StatusCode)
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		now := time.Now()
+		retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After"), now)
+		if !ok {
+			retryAfter = defaultRetryAfter
+		}
+		apiErr.RetryAfter = retryAfter
+
+		rateLimit, err := extractRateLimitData(resp)
+		if err != nil {
+			rateLimit = &v2.RateLimitDescription{}
+		}
+		rateLimit.Status = v2.RateLimitDescription_STATUS_OVERLIMIT
+		rateLimit.ResetAt = timestamppb.New(now.Add(retryAfter))
+		apiErr.rateLimit = rateLimit
+	}
+
+	return apiErr
+}
@@ -0,0 +1,94 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAPIErrorFromValidationResponse(t *testing.T) {
+	fs := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"description":"Validation failed","errors":[{"field":"members","message":"It should contain elements of type positive_integer only","code":"datatype_mismatch"}]}`))
+	}))
+
+	_, err := fs.UpdateAgentGroupMembers(context.Background(), "1", []int64{-1})
+	require.Error(t, err)
+
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	require.Equal(t, "Validation failed", apiErr.Description)
+	require.Equal(t, []FieldError{{
+		Field:   "members",
+		Message: "It should contain elements of type positive_integer only",
+		Code:    "datatype_mismatch",
+	}}, apiErr.Errors)
+
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	require.Equal(t, codes.InvalidArgument, st.Code())
+	require.Len(t, st.Details(), 1)
+	badRequest, ok := st.Details()[0].(*errdetails.BadRequest)
+	require.True(t, ok)
+	require.Equal(t, "members", badRequest.GetFieldViolations()[0].GetField())
+	require.Equal(t, "datatype_mismatch", badRequest.GetFieldViolations()[0].GetReason())
+}
+
+func TestAPIErrorCodes(t *testing.T) {
+	for statusCode, code := range map[int]codes.Code{
+		http.StatusBadRequest:          codes.InvalidArgument,
+		http.StatusUnauthorized:        codes.Unauthenticated,
+		http.StatusForbidden:           codes.PermissionDenied,
+		http.StatusNotFound:            codes.NotFound,
+		http.StatusConflict:            codes.AlreadyExists,
+		http.StatusTooManyRequests:     codes.Unavailable,
+		http.StatusInternalServerError: codes.Unavailable,
+	} {
+		t.Run(http.StatusText(statusCode), func(t *testing.T) {
+			err := &APIError{StatusCode: statusCode}
+			require.Equal(t, code, status.Code(err))
+		})
+	}
+}
+
+func TestAPIErrorTooManyRequestsCarriesRateLimit(t *testing.T) {
+	fs := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"message":"You have exceeded the limit of requests per minute"}`))
+	}))
+
+	_, _, err := fs.GetAgentDetail(context.Background(), "1")
+	require.Error(t, err)
+
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, "You have exceeded the limit of requests per minute", apiErr.Description)
+
+	st := apiErr.GRPCStatus()
+	require.Equal(t, codes.Unavailable, st.Code())
+	require.Len(t, st.Details(), 1)
+	rateLimit, ok := st.Details()[0].(*v2.RateLimitDescription)
+	require.True(t, ok)
+	require.Equal(t, v2.RateLimitDescription_STATUS_OVERLIMIT, rateLimit.GetStatus())
+	require.NotNil(t, rateLimit.GetResetAt())
+}
+
+func TestIsNotFound(t *testing.T) {
+	fs := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+
+	_, err := fs.DeleteRequesterFromRequesterGroup(context.Background(), "1", "2")
+	require.True(t, IsNotFound(err))
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
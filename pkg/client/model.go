@@ -13,14 +13,22 @@ type AgentsAPIData struct {
 }
 
 type Agent struct {
-	Active      bool        `json:"active,omitempty"`
-	Address     string      `json:"address,omitempty"`
-	Email       string      `json:"email,omitempty"`
-	FirstName   string      `json:"first_name,omitempty"`
-	ID          int64       `json:"id,omitempty"`
-	LastName    string      `json:"last_name,omitempty"`
-	Roles       []AgentRole `json:"roles,omitempty"`
-	LastLoginAt time.Time   `json:"last_login_at,omitempty"`
+	Active             bool        `json:"active,omitempty"`
+	Address            string      `json:"address,omitempty"`
+	Email              string      `json:"email,omitempty"`
+	FirstName          string      `json:"first_name,omitempty"`
+	ID                 int64       `json:"id,omitempty"`
+	LastName           string      `json:"last_name,omitempty"`
+	Occasional         bool        `json:"occasional,omitempty"`
+	Roles              []AgentRole `json:"roles,omitempty"`
+	DepartmentIDs      []int64     `json:"department_ids,omitempty"`
+	LocationID         *int64      `json:"location_id,omitempty"`
+	JobTitle           string      `json:"job_title,omitempty"`
+	ReportingManagerID *int64      `json:"reporting_manager_id,omitempty"`
+	LastLoginAt        time.Time   `json:"last_login_at,omitempty"`
+	UpdatedAt          time.Time   `json:"updated_at,omitempty"`
+	// MemberOfPendingApproval are the approval-required groups the agent was added to, pending approval.
+	MemberOfPendingApproval []int64 `json:"member_of_pending_approval,omitempty"`
 }
 
 type AgentDetailAPIData struct {
@@ -49,47 +57,294 @@ type AgentGroupsAPIData struct {
 	Groups []AgentGroup `json:"groups,omitempty"`
 }
 
+// AgentGroup is a group of agents. Agents added to a group with ApprovalRequired set are pending until a group
+// leader approves them, and aren't Members until then.
 type AgentGroup struct {
-	ID          int64   `json:"id,omitempty"`
-	Name        string  `json:"name,omitempty"`
-	Description string  `json:"description,omitempty"`
-	Members     []int64 `json:"members"`
+	ID               int64   `json:"id,omitempty"`
+	Name             string  `json:"name,omitempty"`
+	Description      string  `json:"description,omitempty"`
+	WorkspaceID      int64   `json:"workspace_id,omitempty"`
+	Members          []int64 `json:"members"`
+	Observers        []int64 `json:"observers,omitempty"`
+	Leaders          []int64 `json:"leaders,omitempty"`
+	EscalateTo       *int64  `json:"escalate_to,omitempty"`
+	ApprovalRequired bool    `json:"approval_required,omitempty"`
+}
+
+// UpdateAgentGroupObservers is the body replacing the observers of an agent group.
+type UpdateAgentGroupObservers struct {
+	Observers []int64 `json:"observers"`
+}
+
+// UpdateAgentGroupLeaders is the body replacing the leaders of an agent group.
+type UpdateAgentGroupLeaders struct {
+	Leaders []int64 `json:"leaders"`
+}
+
+// UpdateAgentGroupEscalateTo is the body updating the escalation contact of an agent group. A nil EscalateTo
+// clears it.
+type UpdateAgentGroupEscalateTo struct {
+	EscalateTo *int64 `json:"escalate_to"`
+}
+
+// UpdateAgentGroupAgents is the body replacing any of the members, observers, leaders and escalation contact of an
+// agent group at once. Nil fields are left unchanged, and a non-nil EscalateTo pointing to nil clears it.
+type UpdateAgentGroupAgents struct {
+	Members    *[]int64 `json:"members,omitempty"`
+	Observers  *[]int64 `json:"observers,omitempty"`
+	Leaders    *[]int64 `json:"leaders,omitempty"`
+	EscalateTo **int64  `json:"escalate_to,omitempty"`
 }
 
 type AgentGroupDetailAPIData struct {
 	Group AgentGroup `json:"group,omitempty"`
 }
 
+type WorkspacesAPIData struct {
+	Workspaces []Workspace `json:"workspaces,omitempty"`
+}
+
+type Workspace struct {
+	ID          int64  `json:"id,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	State       string `json:"state,omitempty"`
+	Primary     bool   `json:"primary,omitempty"`
+	Restricted  bool   `json:"restricted,omitempty"`
+}
+
 type UpdateAgentRoles struct {
 	Roles []AgentRole `json:"roles"`
 }
 
-type requestersAPIData struct {
+// UpdateAgentOccasional switches an agent between a full-time license and day passes.
+type UpdateAgentOccasional struct {
+	Occasional bool `json:"occasional"`
+}
+
+type RequestersAPIData struct {
 	Requesters []Requesters `json:"requesters,omitempty"`
 }
 
 type Requesters struct {
-	Active       bool   `json:"active,omitempty"`
-	Address      string `json:"address,omitempty"`
-	FirstName    string `json:"first_name,omitempty"`
-	ID           int64  `json:"id,omitempty"`
-	IsAgent      bool   `json:"is_agent,omitempty"`
-	LastName     string `json:"last_name,omitempty"`
-	PrimaryEmail string `json:"primary_email,omitempty"`
+	Active        bool      `json:"active,omitempty"`
+	Address       string    `json:"address,omitempty"`
+	FirstName     string    `json:"first_name,omitempty"`
+	ID            int64     `json:"id,omitempty"`
+	IsAgent       bool      `json:"is_agent,omitempty"`
+	LastName      string    `json:"last_name,omitempty"`
+	PrimaryEmail  string    `json:"primary_email,omitempty"`
+	DepartmentIDs []int64   `json:"department_ids,omitempty"`
+	LocationID    *int64    `json:"location_id,omitempty"`
+	UpdatedAt     time.Time `json:"updated_at,omitempty"`
+
+	JobTitle           string `json:"job_title,omitempty"`
+	ReportingManagerID *int64 `json:"reporting_manager_id,omitempty"`
+}
+
+// CreateAgent is the body creating an agent. Occasional agents use day passes instead of a full-time license, and
+// MemberOf are the groups the agent is added to.
+type CreateAgent struct {
+	FirstName          string      `json:"first_name"`
+	LastName           string      `json:"last_name,omitempty"`
+	Email              string      `json:"email"`
+	Occasional         bool        `json:"occasional"`
+	JobTitle           string      `json:"job_title,omitempty"`
+	DepartmentIDs      []int64     `json:"department_ids,omitempty"`
+	LocationID         *int64      `json:"location_id,omitempty"`
+	ReportingManagerID *int64      `json:"reporting_manager_id,omitempty"`
+	Roles              []AgentRole `json:"roles"`
+	MemberOf           []int64     `json:"member_of,omitempty"`
+}
+
+// CreateRequester is the body creating a requester.
+type CreateRequester struct {
+	FirstName          string  `json:"first_name"`
+	LastName           string  `json:"last_name,omitempty"`
+	PrimaryEmail       string  `json:"primary_email"`
+	JobTitle           string  `json:"job_title,omitempty"`
+	DepartmentIDs      []int64 `json:"department_ids,omitempty"`
+	LocationID         *int64  `json:"location_id,omitempty"`
+	ReportingManagerID *int64  `json:"reporting_manager_id,omitempty"`
+}
+
+type RequesterDetailAPIData struct {
+	Requester Requesters `json:"requester,omitempty"`
+}
+
+type DepartmentsAPIData struct {
+	Departments []Department `json:"departments,omitempty"`
+}
+
+// Department is a Freshservice department, called a company when the account is in MSP mode.
+type Department struct {
+	ID          int64  `json:"id,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	HeadUserID  int64  `json:"head_user_id,omitempty"`
+	PrimeUserID int64  `json:"prime_user_id,omitempty"`
 }
 
-type RequesterGroupsAPIData struct {
-	RequesterGroups []RequesterGroup `json:"requester_groups,omitempty"`
+// UpdateUserDepartments is the body updating the departments of an agent or a requester.
+type UpdateUserDepartments struct {
+	DepartmentIDs []int64 `json:"department_ids"`
 }
 
-type RequesterGroup struct {
+type LocationsAPIData struct {
+	Locations []Location `json:"locations,omitempty"`
+}
+
+type Location struct {
+	ID               int64            `json:"id,omitempty"`
+	Name             string           `json:"name,omitempty"`
+	ParentLocationID *int64           `json:"parent_location_id,omitempty"`
+	PrimaryContactID *int64           `json:"primary_contact_id,omitempty"`
+	Address          *LocationAddress `json:"address,omitempty"`
+}
+
+type LocationAddress struct {
+	Line1   string `json:"line1,omitempty"`
+	Line2   string `json:"line2,omitempty"`
+	City    string `json:"city,omitempty"`
+	State   string `json:"state,omitempty"`
+	Country string `json:"country,omitempty"`
+	Zipcode string `json:"zipcode,omitempty"`
+}
+
+// UpdateUserLocation is the body updating the location of an agent or a requester. A nil LocationID clears it.
+type UpdateUserLocation struct {
+	LocationID *int64 `json:"location_id"`
+}
+
+type ApplicationsAPIData struct {
+	Applications []Application `json:"applications,omitempty"`
+}
+
+// Application is a software application tracked by SaaS Management.
+type Application struct {
+	ID              int64  `json:"id,omitempty"`
+	Name            string `json:"name,omitempty"`
+	Description     string `json:"description,omitempty"`
+	ApplicationType string `json:"application_type,omitempty"`
+	Status          string `json:"status,omitempty"`
+	Category        string `json:"category,omitempty"`
+	ManagedByID     *int64 `json:"managed_by_id,omitempty"`
+	UserCount       int64  `json:"user_count,omitempty"`
+	WorkspaceID     int64  `json:"workspace_id,omitempty"`
+}
+
+type ApplicationUsersAPIData struct {
+	ApplicationUsers []ApplicationUser `json:"application_users"`
+}
+
+// ApplicationUser is a user of an application. UserID is the ID of an agent or a requester.
+type ApplicationUser struct {
+	ID        int64      `json:"id,omitempty"`
+	UserID    int64      `json:"user_id"`
+	LicenseID *int64     `json:"license_id,omitempty"`
+	Source    string     `json:"source,omitempty"`
+	FirstUsed *time.Time `json:"first_used,omitempty"`
+	LastUsed  *time.Time `json:"last_used,omitempty"`
+}
+
+// ApplicationUserLicense is a user of an application in a bulk update. A nil LicenseID unassigns the user's license.
+type ApplicationUserLicense struct {
+	UserID    int64  `json:"user_id"`
+	LicenseID *int64 `json:"license_id"`
+}
+
+type UpdateApplicationUsers struct {
+	ApplicationUsers []ApplicationUserLicense `json:"application_users"`
+}
+
+type ApplicationLicensesAPIData struct {
+	Licenses []ApplicationLicense `json:"licenses"`
+}
+
+// ApplicationLicense is a license of an application, purchased through a contract. Quantity is the number of
+// purchased seats and AllocatedCount the number assigned to users.
+type ApplicationLicense struct {
+	ID             int64  `json:"id,omitempty"`
+	Name           string `json:"name,omitempty"`
+	ContractID     *int64 `json:"contract_id,omitempty"`
+	Quantity       int64  `json:"quantity,omitempty"`
+	AllocatedCount int64  `json:"allocated_count,omitempty"`
+}
+
+type AssetTypesAPIData struct {
+	AssetTypes []AssetType `json:"asset_types,omitempty"`
+}
+
+type AssetType struct {
+	ID                int64  `json:"id,omitempty"`
+	Name              string `json:"name,omitempty"`
+	Description       string `json:"description,omitempty"`
+	ParentAssetTypeID *int64 `json:"parent_asset_type_id,omitempty"`
+	Visible           bool   `json:"visible,omitempty"`
+}
+
+type AssetsAPIData struct {
+	Assets []Asset `json:"assets,omitempty"`
+}
+
+type AssetDetailAPIData struct {
+	Asset Asset `json:"asset,omitempty"`
+}
+
+// Asset is a CMDB asset. It is used by UserID, an agent or a requester, and managed by AgentID and GroupID.
+// Assets are addressed by DisplayID in the API.
+type Asset struct {
 	ID          int64  `json:"id,omitempty"`
+	DisplayID   int64  `json:"display_id,omitempty"`
 	Name        string `json:"name,omitempty"`
 	Description string `json:"description,omitempty"`
-	Type        string `json:"type,omitempty"`
+	AssetTypeID int64  `json:"asset_type_id,omitempty"`
+	AssetTag    string `json:"asset_tag,omitempty"`
+	Impact      string `json:"impact,omitempty"`
+	UserID      *int64 `json:"user_id,omitempty"`
+	AgentID     *int64 `json:"agent_id,omitempty"`
+	GroupID     *int64 `json:"group_id,omitempty"`
+	WorkspaceID int64  `json:"workspace_id,omitempty"`
+}
+
+// UpdateAssetUser is the body updating the user of an asset. A nil UserID clears it.
+type UpdateAssetUser struct {
+	UserID *int64 `json:"user_id"`
+}
+
+// UpdateAssetAgent is the body updating the managing agent of an asset. A nil AgentID clears it.
+type UpdateAssetAgent struct {
+	AgentID *int64 `json:"agent_id"`
+}
+
+// UpdateAssetGroup is the body updating the managing agent group of an asset. A nil GroupID clears it.
+type UpdateAssetGroup struct {
+	GroupID *int64 `json:"group_id"`
+}
+
+type RequesterGroupsAPIData struct {
+	RequesterGroups []RequesterGroup `json:"requester_groups,omitempty"`
+}
+
+type RequesterGroupDetailAPIData struct {
+	RequesterGroup RequesterGroup `json:"requester_group,omitempty"`
+}
+
+// Requester group types. Rule-based groups get their members from their rules and can't be added to or removed from.
+const (
+	RequesterGroupTypeManual    = "manual"
+	RequesterGroupTypeRuleBased = "rule_based"
+)
+
+type RequesterGroup struct {
+	ID          int64       `json:"id,omitempty"`
+	Name        string      `json:"name,omitempty"`
+	Description string      `json:"description,omitempty"`
+	Type        string      `json:"type,omitempty"`
+	Rules       interface{} `json:"rules,omitempty"`
 }
 
-type requesterGroupMembersAPIData struct {
+type RequesterGroupMembersAPIData struct {
 	Requesters []RequesterGroupMember `json:"requesters,omitempty"`
 }
 
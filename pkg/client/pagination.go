@@ -1,8 +1,16 @@
 package client
 
 import (
+	"context"
+	"fmt"
+	"iter"
+	"net/http"
 	"net/url"
 	"strconv"
+	"time"
+
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/tomnomnom/linkheader"
 )
 
 // By default, the number of objects returned per page is 30.
@@ -43,3 +51,179 @@ func WithQueryParam(key string, value string) ReqOpt {
 		reqURL.RawQuery = q.Encode()
 	}
 }
+
+// WithWorkspaceID scopes a request to a single workspace.
+// https://api.freshservice.com/v2/#workspaces
+func WithWorkspaceID(workspaceID string) ReqOpt {
+	return WithQueryParam("workspace_id", workspaceID)
+}
+
+// WithAssetTypeID limits assets to a single asset type.
+// https://api.freshservice.com/v2/#filter_assets
+func WithAssetTypeID(assetTypeID string) ReqOpt {
+	return WithQueryParam("filter", fmt.Sprintf(`"asset_type_id:%s"`, assetTypeID))
+}
+
+// WithActive limits agents to the active or the deactivated ones. Without it, /agents only lists active agents.
+// https://api.freshservice.com/v2/#list_all_agents
+func WithActive(active bool) ReqOpt {
+	return WithQueryParam("active", strconv.FormatBool(active))
+}
+
+// WithUpdatedSince limits agents and requesters to the ones updated at or after since.
+func WithUpdatedSince(since time.Time) ReqOpt {
+	return WithQueryParam("updated_since", since.UTC().Format(time.RFC3339))
+}
+
+// nextPageLink returns the URL of the `Link: <...>; rel="next"` header, or nil on the last page.
+func nextPageLink(header http.Header) (*url.URL, error) {
+	for _, link := range linkheader.Parse(header.Get("Link")) {
+		if link.Rel == "next" {
+			return url.Parse(link.URL)
+		}
+	}
+	return nil, nil
+}
+
+// listPage fetches a single page of a list endpoint into the response envelope T
+// and returns the page number of the next page, or "" on the last page.
+func listPage[T any](
+	ctx context.Context,
+	f *FreshServiceClient,
+	pathElems []string,
+	opts PageOptions,
+	reqOpts ...ReqOpt,
+) (*T, string, annotations.Annotations, error) {
+	listUrl, err := url.JoinPath(f.baseUrl, pathElems...)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	reqOpts = append([]ReqOpt{WithPage(opts.Page), WithPageLimit(opts.PerPage)}, reqOpts...)
+
+	var res *T
+	nextPage, annotation, err := f.getListAPIData(ctx, listUrl, &res, reqOpts...)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return res, nextPage, annotation, nil
+}
+
+// Pages returns an iterator over every page of the list endpoint at pathElems, decoded into the
+// response envelope T. It requests the maximum page size and follows the `Link: rel=next` header
+// until the last page. Iteration stops after the first error, which is yielded with a nil page.
+//
+//	for page, err := range client.Pages[client.AgentsAPIData](ctx, fs, []string{"agents"}) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+func Pages[T any](ctx context.Context, f *FreshServiceClient, pathElems []string, reqOpts ...ReqOpt) iter.Seq2[*T, error] {
+	return func(yield func(*T, error) bool) {
+		pageUrl, err := url.JoinPath(f.baseUrl, pathElems...)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		pageOpts := append([]ReqOpt{WithPageLimit(ItemsPerPage)}, reqOpts...)
+		for pageUrl != "" {
+			var res *T
+			header, _, err := f.doRequest(ctx, http.MethodGet, pageUrl, &res, nil, pageOpts...)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			if !yield(res, nil) {
+				return
+			}
+
+			nextPageUrl, err := nextPageLink(header)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if nextPageUrl == nil {
+				return
+			}
+
+			currentUrl, err := url.Parse(pageUrl)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			nextUrl := currentUrl.ResolveReference(nextPageUrl).String()
+			if nextUrl == pageUrl {
+				return
+			}
+
+			// The next link already carries the query string of the previous request.
+			pageUrl = nextUrl
+			pageOpts = nil
+		}
+	}
+}
+
+// AgentUserPages iterates over every page of agents. Pass WithActive(false) to list the deactivated ones.
+// https://api.freshservice.com/v2/#list_all_agents
+func (f *FreshServiceClient) AgentUserPages(ctx context.Context, reqOpts ...ReqOpt) iter.Seq2[*AgentsAPIData, error] {
+	return Pages[AgentsAPIData](withOperation(ctx, "AgentUserPages"), f, []string{"agents"}, reqOpts...)
+}
+
+// RequesterUserPages iterates over every page of requesters.
+// https://api.freshservice.com/v2/#list_all_requesters
+func (f *FreshServiceClient) RequesterUserPages(ctx context.Context) iter.Seq2[*RequestersAPIData, error] {
+	return Pages[RequestersAPIData](withOperation(ctx, "RequesterUserPages"), f, []string{"requesters"})
+}
+
+// AgentGroupPages iterates over every page of agent groups. Pass WithWorkspaceID to list a single workspace.
+// https://api.freshservice.com/v2/#view_all_group
+func (f *FreshServiceClient) AgentGroupPages(ctx context.Context, reqOpts ...ReqOpt) iter.Seq2[*AgentGroupsAPIData, error] {
+	return Pages[AgentGroupsAPIData](withOperation(ctx, "AgentGroupPages"), f, []string{"groups"}, reqOpts...)
+}
+
+// WorkspacePages iterates over every page of workspaces.
+// https://api.freshservice.com/v2/#list_all_workspaces
+func (f *FreshServiceClient) WorkspacePages(ctx context.Context) iter.Seq2[*WorkspacesAPIData, error] {
+	return Pages[WorkspacesAPIData](withOperation(ctx, "WorkspacePages"), f, []string{"workspaces"})
+}
+
+// RolePages iterates over every page of roles.
+// https://api.freshservice.com/v2/#view_all_role
+func (f *FreshServiceClient) RolePages(ctx context.Context) iter.Seq2[*RolesAPIData, error] {
+	return Pages[RolesAPIData](withOperation(ctx, "RolePages"), f, []string{"roles"})
+}
+
+// RequesterGroupPages iterates over every page of requester groups.
+// https://api.freshservice.com/v2/#view_all_requester_group
+func (f *FreshServiceClient) RequesterGroupPages(ctx context.Context) iter.Seq2[*RequesterGroupsAPIData, error] {
+	return Pages[RequesterGroupsAPIData](withOperation(ctx, "RequesterGroupPages"), f, []string{"requester_groups"})
+}
+
+// RequesterGroupMemberPages iterates over every page of members of a requester group.
+// https://api.freshservice.com/v2/#list_members_of_requester_group
+func (f *FreshServiceClient) RequesterGroupMemberPages(ctx context.Context, requesterGroupId string) iter.Seq2[*RequesterGroupMembersAPIData, error] {
+	return Pages[RequesterGroupMembersAPIData](withOperation(ctx, "RequesterGroupMemberPages"), f, []string{"requester_groups", requesterGroupId, "members"})
+}
+
+// ApplicationUserPages iterates over every page of users of an application.
+// https://api.freshservice.com/v2/#list_all_software_users
+func (f *FreshServiceClient) ApplicationUserPages(ctx context.Context, applicationId string) iter.Seq2[*ApplicationUsersAPIData, error] {
+	return Pages[ApplicationUsersAPIData](withOperation(ctx, "ApplicationUserPages"), f, []string{"applications", applicationId, "users"})
+}
+
+// ApplicationLicensePages iterates over every page of licenses of an application.
+// https://api.freshservice.com/v2/#list_all_software_licenses
+func (f *FreshServiceClient) ApplicationLicensePages(ctx context.Context, applicationId string) iter.Seq2[*ApplicationLicensesAPIData, error] {
+	return Pages[ApplicationLicensesAPIData](withOperation(ctx, "ApplicationLicensePages"), f, []string{"applications", applicationId, "licenses"})
+}
+
+// ServiceCatalogItemPages iterates over every page of service catalog items, honoring the configured category.
+// Pass WithWorkspaceID to list a single workspace.
+func (f *FreshServiceClient) ServiceCatalogItemPages(ctx context.Context, reqOpts ...ReqOpt) iter.Seq2[*ServiceCatalogItemsListResponse, error] {
+	reqOpts = append(f.serviceCatalogItemFilters(), reqOpts...)
+	return Pages[ServiceCatalogItemsListResponse](withOperation(ctx, "ServiceCatalogItemPages"), f, []string{"service_catalog", "items"}, reqOpts...)
+}
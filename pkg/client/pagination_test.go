@@ -0,0 +1,70 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestClient(t *testing.T, handler http.Handler) *FreshServiceClient {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	fs, err := New(context.Background(), NewClient(nil).WithBearerToken("token").WithDomain("test").WithBaseURL(server.URL))
+	require.NoError(t, err)
+	return fs
+}
+
+func TestPagesFollowsNextLink(t *testing.T) {
+	const lastPage = 3
+	fs := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/agents", r.URL.Path)
+		require.Equal(t, strconv.Itoa(ItemsPerPage), r.URL.Query().Get("per_page"))
+
+		page, err := strconv.Atoi(r.URL.Query().Get("page"))
+		if err != nil {
+			page = 1
+		}
+		if page < lastPage {
+			next := *r.URL
+			q := next.Query()
+			q.Set("page", strconv.Itoa(page+1))
+			next.RawQuery = q.Encode()
+			w.Header().Set("Link", fmt.Sprintf(`<http://%s%s>; rel="next"`, r.Host, next.String()))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(AgentsAPIData{Agents: []Agent{{ID: int64(page)}}})
+	}))
+
+	var ids []int64
+	for page, err := range fs.AgentUserPages(context.Background()) {
+		require.NoError(t, err)
+		for _, agent := range page.Agents {
+			ids = append(ids, agent.ID)
+		}
+	}
+	require.Equal(t, []int64{1, 2, 3}, ids)
+}
+
+func TestPagesStopsOnError(t *testing.T) {
+	fs := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"message":"forbidden"}`))
+	}))
+
+	calls := 0
+	for page, err := range fs.RolePages(context.Background()) {
+		calls++
+		require.Error(t, err)
+		require.Nil(t, page)
+	}
+	require.Equal(t, 1, calls)
+}
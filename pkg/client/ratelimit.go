@@ -0,0 +1,198 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+const (
+	// Freshservice rate limits are per account, per minute.
+	// https://api.freshservice.com/v2/#rate_limit
+	rateLimitWindow = time.Minute
+	// Once less than this percentage of the account budget remains, requests are spread out over the window.
+	slowdownPercent = 20
+	// How many times a request is retried after a 429 response.
+	maxRateLimitRetries = 3
+	// Used when a 429 response does not say how long to wait.
+	defaultRetryAfter = 10 * time.Second
+)
+
+// rateGovernor paces requests against the per-minute budget that Freshservice shares between every
+// integration on the account. It caps this client to a percentage of that budget, slows down as the
+// account-wide remaining count approaches zero, and holds all requests while a 429 Retry-After is pending.
+type rateGovernor struct {
+	mu sync.Mutex
+	// Percentage (1-100) of the account's per-minute budget this client may use.
+	percent int64
+	// Last X-RateLimit-Total and X-RateLimit-Remaining seen, and when.
+	limit      int64
+	remaining  int64
+	observedAt time.Time
+	// Requests are held until this time after a 429.
+	blockedUntil time.Time
+	// Times this client's requests in the current window were let through, oldest first. Requests still waiting
+	// for their turn are included at the time they were given.
+	sent []time.Time
+	// Time the last request was let through, or is due to be.
+	lastSent time.Time
+	now      func() time.Time
+	sleep    func(ctx context.Context, d time.Duration) error
+}
+
+func newRateGovernor(percent int) *rateGovernor {
+	if percent <= 0 || percent > 100 {
+		percent = 100
+	}
+	return &rateGovernor{
+		percent: int64(percent),
+		now:     time.Now,
+		sleep:   sleepContext,
+	}
+}
+
+// wait blocks until the next request may be sent. Each request is given its time to be sent up front, so that
+// it sleeps at most once and concurrent requests are spaced out rather than released together.
+func (g *rateGovernor) wait(ctx context.Context) error {
+	g.mu.Lock()
+	now := g.now()
+	d := g.delay(now)
+	at := now.Add(d)
+	g.sent = append(g.sent, at)
+	g.lastSent = at
+	g.mu.Unlock()
+
+	if d <= 0 {
+		return nil
+	}
+
+	ctxzap.Extract(ctx).Debug("freshservice-connector: throttling request", zap.Duration("delay", d))
+	if err := g.sleep(ctx, d); err != nil {
+		// The request won't be sent, so its time is given back. Unless a later request has been let through
+		// since, the previous request becomes the last one sent again.
+		g.mu.Lock()
+		if i := slices.Index(g.sent, at); i >= 0 {
+			g.sent = slices.Delete(g.sent, i, i+1)
+		}
+		if g.lastSent.Equal(at) {
+			g.lastSent = time.Time{}
+			if len(g.sent) > 0 {
+				g.lastSent = g.sent[len(g.sent)-1]
+			}
+		}
+		g.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// sleepContext sleeps for d, or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// delay returns how long a request at now waits before it is sent. Requests are let through in order, so it is
+// never sent before the previous one. Callers must hold g.mu.
+func (g *rateGovernor) delay(now time.Time) time.Duration {
+	at := now
+	if g.blockedUntil.After(at) {
+		at = g.blockedUntil
+	}
+
+	windowStart := now.Add(-rateLimitWindow)
+	for len(g.sent) > 0 && !g.sent[0].After(windowStart) {
+		g.sent = g.sent[1:]
+	}
+
+	// Headers older than a window describe a budget that has since been reset.
+	if g.limit > 0 && g.observedAt.After(windowStart) {
+		// Once the budget is used up, a request waits for the oldest one it counts to leave the window.
+		if budget := g.budget(); int64(len(g.sent)) >= budget {
+			at = later(at, g.sent[int64(len(g.sent))-budget].Add(rateLimitWindow))
+		}
+
+		// Running low, requests are spread out over the window. A 429 received since the last request already
+		// says when the next one may go.
+		if g.remaining*100 < g.limit*slowdownPercent && !g.blockedUntil.After(g.lastSent) {
+			remaining := max(g.remaining, 0)
+			at = later(at, g.lastSent.Add(rateLimitWindow/time.Duration(remaining+1)))
+		}
+	}
+
+	return later(at, g.lastSent).Sub(now)
+}
+
+func later(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+// budget is this client's share of the account's per-minute budget. Callers must hold g.mu.
+func (g *rateGovernor) budget() int64 {
+	return max(g.limit*g.percent/100, 1)
+}
+
+// observe records the rate limit headers of a response. It returns true if the response was a 429,
+// in which case requests are held until the Retry-After interval has passed.
+func (g *rateGovernor) observe(resp *http.Response) bool {
+	if resp == nil {
+		return false
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+	if limit, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Total"), 10, 64); err == nil {
+		g.limit = limit
+		g.observedAt = now
+	}
+	if remaining, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Remaining"), 10, 64); err == nil {
+		g.remaining = remaining
+		g.observedAt = now
+	}
+
+	if resp.StatusCode != http.StatusTooManyRequests {
+		return false
+	}
+
+	retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After"), now)
+	if !ok {
+		retryAfter = defaultRetryAfter
+	}
+	if until := now.Add(retryAfter); until.After(g.blockedUntil) {
+		g.blockedUntil = until
+	}
+	g.remaining = 0
+	return true
+}
+
+// parseRetryAfter parses a Retry-After header, which Freshservice sends as a number of seconds.
+// HTTP dates are accepted as well.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Duration(max(seconds, 0)) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(at.Sub(now), 0), true
+	}
+	return 0, false
+}
@@ -0,0 +1,122 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func rateLimitedResponse(status int, limit, remaining int64, retryAfter string) *http.Response {
+	header := http.Header{}
+	header.Set("X-RateLimit-Total", strconv.FormatInt(limit, 10))
+	header.Set("X-RateLimit-Remaining", strconv.FormatInt(remaining, 10))
+	if retryAfter != "" {
+		header.Set("Retry-After", retryAfter)
+	}
+	return &http.Response{StatusCode: status, Header: header}
+}
+
+func TestRateGovernorDelay(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("no headers seen", func(t *testing.T) {
+		g := newRateGovernor(100)
+		require.Zero(t, g.delay(now))
+	})
+
+	t.Run("plenty remaining", func(t *testing.T) {
+		g := newRateGovernor(100)
+		g.now = func() time.Time { return now }
+		g.observe(rateLimitedResponse(http.StatusOK, 100, 90, ""))
+		require.Zero(t, g.delay(now))
+	})
+
+	t.Run("slows down as remaining approaches zero", func(t *testing.T) {
+		g := newRateGovernor(100)
+		g.now = func() time.Time { return now }
+		g.observe(rateLimitedResponse(http.StatusOK, 100, 9, ""))
+		g.sent = []time.Time{now.Add(-time.Second)}
+		g.lastSent = now.Add(-time.Second)
+		require.Equal(t, rateLimitWindow/10-time.Second, g.delay(now))
+		require.Zero(t, g.delay(now.Add(rateLimitWindow/10)))
+	})
+
+	t.Run("caps to a percentage of the budget", func(t *testing.T) {
+		g := newRateGovernor(10)
+		g.now = func() time.Time { return now }
+		g.observe(rateLimitedResponse(http.StatusOK, 100, 100, ""))
+		for i := 9; i >= 0; i-- {
+			g.sent = append(g.sent, now.Add(-time.Duration(i)*time.Second))
+		}
+		require.Equal(t, rateLimitWindow-9*time.Second, g.delay(now))
+	})
+
+	t.Run("waits for retry-after in seconds", func(t *testing.T) {
+		g := newRateGovernor(100)
+		g.now = func() time.Time { return now }
+		require.True(t, g.observe(rateLimitedResponse(http.StatusTooManyRequests, 100, 0, "30")))
+		require.Equal(t, 30*time.Second, g.delay(now))
+		require.Zero(t, g.delay(now.Add(rateLimitWindow+time.Second)))
+	})
+}
+
+func TestRateGovernorWaitSpacesRequests(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	start := now
+	g := newRateGovernor(100)
+	g.now = func() time.Time { return now }
+	var sleeps int
+	g.sleep = func(_ context.Context, d time.Duration) error {
+		sleeps++
+		now = now.Add(d)
+		return nil
+	}
+	g.observe(rateLimitedResponse(http.StatusOK, 100, 4, ""))
+
+	// With 4 requests left, requests are a fifth of the window apart, each sleeping at most once.
+	spacing := rateLimitWindow / 5
+	for i := 1; i <= 5; i++ {
+		require.NoError(t, g.wait(context.Background()))
+		require.LessOrEqual(t, sleeps, i)
+		require.Equal(t, time.Duration(i-1)*spacing, now.Sub(start))
+	}
+
+	// A cancelled request doesn't hold up the next one.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	g.sleep = sleepContext
+	require.ErrorIs(t, g.wait(ctx), context.Canceled)
+	require.Len(t, g.sent, 5)
+
+	// The next request is spaced from the last one sent, not from the cancelled one.
+	g.sleep = func(_ context.Context, d time.Duration) error {
+		now = now.Add(d)
+		return nil
+	}
+	require.NoError(t, g.wait(context.Background()))
+	require.Equal(t, 5*spacing, now.Sub(start))
+}
+
+func TestDoRequestRetriesAfterTooManyRequests(t *testing.T) {
+	var calls atomic.Int32
+	fs := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"message":"rate limited"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"roles":[{"id":1,"name":"Admin"}]}`))
+	}))
+
+	roles, _, _, err := fs.ListRoles(context.Background(), PageOptions{})
+	require.NoError(t, err)
+	require.Len(t, roles.Roles, 1)
+	require.EqualValues(t, 2, calls.Load())
+}
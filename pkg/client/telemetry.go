@@ -0,0 +1,132 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/conductorone/baton-sdk/pkg/metrics"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "baton-freshservice/pkg.client"
+
+const (
+	requestDurationName = "freshservice.request.duration"
+	requestDurationDesc = "Duration of Freshservice API operations, including rate limit waits and retries"
+	requestCountName    = "freshservice.requests"
+	requestCountDesc    = "Number of Freshservice API operations"
+	retryCountName      = "freshservice.request.retries"
+	retryCountDesc      = "Number of Freshservice API requests retried after being rate limited"
+	rateLimitName       = "freshservice.rate_limit.remaining"
+	rateLimitDesc       = "Remaining Freshservice API requests in the current rate limit window"
+)
+
+type operationKey struct{}
+
+// withOperation names the logical API operation, e.g. ListAgentUsers, that spans and metrics of the requests made
+// with ctx are labelled with.
+func withOperation(ctx context.Context, operation string) context.Context {
+	return context.WithValue(ctx, operationKey{}, operation)
+}
+
+// operationName returns the operation set by withOperation. Requests made without one are named after the method
+// and the endpoint path, with IDs replaced so that the name stays low-cardinality.
+func operationName(ctx context.Context, method string, baseURL string, u *url.URL) string {
+	if operation, ok := ctx.Value(operationKey{}).(string); ok {
+		return operation
+	}
+
+	path := u.Path
+	if base, err := url.Parse(baseURL); err == nil {
+		path = strings.TrimPrefix(path, base.Path)
+	}
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, segment := range segments {
+		if _, err := strconv.ParseInt(segment, 10, 64); err == nil {
+			segments[i] = "{id}"
+		}
+	}
+	return method + " /" + strings.Join(segments, "/")
+}
+
+// telemetry records a span and metrics for every API operation.
+type telemetry struct {
+	tracer    trace.Tracer
+	duration  metrics.Int64Histogram
+	requests  metrics.Int64Counter
+	retries   metrics.Int64Counter
+	rateLimit metrics.Int64Gauge
+}
+
+// newTelemetry uses the global OpenTelemetry providers when tracerProvider or handler are nil.
+func newTelemetry(ctx context.Context, tracerProvider trace.TracerProvider, handler metrics.Handler) *telemetry {
+	if tracerProvider == nil {
+		tracerProvider = otel.GetTracerProvider()
+	}
+	if handler == nil {
+		handler = metrics.NewOtelHandler(ctx, otel.GetMeterProvider(), instrumentationName)
+	}
+
+	return &telemetry{
+		tracer:    tracerProvider.Tracer(instrumentationName),
+		duration:  handler.Int64Histogram(requestDurationName, requestDurationDesc, metrics.Milliseconds),
+		requests:  handler.Int64Counter(requestCountName, requestCountDesc, metrics.Dimensionless),
+		retries:   handler.Int64Counter(retryCountName, retryCountDesc, metrics.Dimensionless),
+		rateLimit: handler.Int64Gauge(rateLimitName, rateLimitDesc, metrics.Dimensionless),
+	}
+}
+
+// start starts the span of an operation. The returned func ends it and records its metrics.
+func (t *telemetry) start(ctx context.Context, operation, method string) (context.Context, func(resp *http.Response, retries int, err error)) {
+	started := time.Now()
+	ctx, span := t.tracer.Start(ctx, "freshservice."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("freshservice.operation", operation),
+			attribute.String("http.request.method", method),
+		),
+	)
+
+	return ctx, func(resp *http.Response, retries int, err error) {
+		defer span.End()
+
+		status := 0
+		if resp != nil {
+			status = resp.StatusCode
+		}
+		tags := map[string]string{
+			"operation":   operation,
+			"method":      method,
+			"status_code": strconv.Itoa(status),
+		}
+		span.SetAttributes(
+			attribute.Int("http.response.status_code", status),
+			attribute.Int("freshservice.retries", retries),
+		)
+
+		t.duration.Record(ctx, time.Since(started).Milliseconds(), tags)
+		t.requests.Add(ctx, 1, tags)
+		if retries > 0 {
+			t.retries.Add(ctx, int64(retries), map[string]string{"operation": operation})
+		}
+
+		if resp != nil {
+			if remaining, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Remaining"), 10, 64); err == nil {
+				span.SetAttributes(attribute.Int64("freshservice.rate_limit.remaining", remaining))
+				t.rateLimit.Observe(ctx, remaining, nil)
+			}
+		}
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+}
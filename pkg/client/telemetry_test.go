@@ -0,0 +1,131 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/conductorone/baton-sdk/pkg/metrics"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordingHandler is a metrics.Handler that keeps the recorded values by metric name.
+type recordingHandler struct {
+	mu     sync.Mutex
+	values map[string][]recordedValue
+}
+
+type recordedValue struct {
+	value int64
+	tags  map[string]string
+}
+
+func (h *recordingHandler) record(name string, value int64, tags map[string]string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.values == nil {
+		h.values = make(map[string][]recordedValue)
+	}
+	h.values[name] = append(h.values[name], recordedValue{value: value, tags: tags})
+}
+
+func (h *recordingHandler) Int64Counter(name string, _ string, _ metrics.Unit) metrics.Int64Counter {
+	return recorder{h: h, name: name}
+}
+
+func (h *recordingHandler) Int64Gauge(name string, _ string, _ metrics.Unit) metrics.Int64Gauge {
+	return recorder{h: h, name: name}
+}
+
+func (h *recordingHandler) Int64Histogram(name string, _ string, _ metrics.Unit) metrics.Int64Histogram {
+	return recorder{h: h, name: name}
+}
+
+func (h *recordingHandler) WithTags(map[string]string) metrics.Handler {
+	return h
+}
+
+type recorder struct {
+	h    *recordingHandler
+	name string
+}
+
+func (r recorder) Add(_ context.Context, value int64, tags map[string]string) {
+	r.h.record(r.name, value, tags)
+}
+
+func (r recorder) Observe(_ context.Context, value int64, tags map[string]string) {
+	r.h.record(r.name, value, tags)
+}
+
+func (r recorder) Record(_ context.Context, value int64, tags map[string]string) {
+	r.h.record(r.name, value, tags)
+}
+
+func TestOperationsAreTraced(t *testing.T) {
+	t.Setenv("BATON_DISABLE_HTTP_CACHE", "true")
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		if calls == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"message":"rate limited"}`))
+			return
+		}
+		w.Header().Set("X-RateLimit-Total", "100")
+		w.Header().Set("X-RateLimit-Remaining", "42")
+		_ = json.NewEncoder(w).Encode(AgentDetailAPIData{Agent: Agent{ID: 1}})
+	}))
+	t.Cleanup(server.Close)
+
+	spans := tracetest.NewSpanRecorder()
+	handler := &recordingHandler{}
+	fs, err := New(context.Background(), NewClient(nil).
+		WithBearerToken("token").
+		WithDomain("test").
+		WithBaseURL(server.URL).
+		WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))).
+		WithMetricsHandler(handler))
+	require.NoError(t, err)
+
+	_, _, err = fs.GetAgentDetail(context.Background(), "1")
+	require.NoError(t, err)
+
+	ended := spans.Ended()
+	require.Len(t, ended, 1)
+	require.Equal(t, "freshservice.GetAgentDetail", ended[0].Name())
+	attrs := attribute.NewSet(ended[0].Attributes()...)
+	status, _ := attrs.Value("http.response.status_code")
+	require.Equal(t, int64(http.StatusOK), status.AsInt64())
+	retries, _ := attrs.Value("freshservice.retries")
+	require.Equal(t, int64(1), retries.AsInt64())
+	remaining, _ := attrs.Value("freshservice.rate_limit.remaining")
+	require.Equal(t, int64(42), remaining.AsInt64())
+
+	require.Equal(t, []recordedValue{{value: 1, tags: map[string]string{
+		"operation":   "GetAgentDetail",
+		"method":      http.MethodGet,
+		"status_code": "200",
+	}}}, handler.values[requestCountName])
+	require.Equal(t, int64(1), handler.values[retryCountName][0].value)
+	require.Equal(t, int64(42), handler.values[rateLimitName][0].value)
+}
+
+func TestOperationNameFallsBackToPathTemplate(t *testing.T) {
+	u, err := url.Parse("https://test.freshservice.com/api/v2/requester_groups/12/members/34")
+	require.NoError(t, err)
+
+	require.Equal(t, "DELETE /requester_groups/{id}/members/{id}",
+		operationName(context.Background(), http.MethodDelete, "https://test.freshservice.com/api/v2", u))
+	require.Equal(t, "ListRoles",
+		operationName(withOperation(context.Background(), "ListRoles"), http.MethodGet, "https://test.freshservice.com/api/v2", u))
+}
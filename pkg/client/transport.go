@@ -0,0 +1,161 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/conductorone/baton-sdk/pkg/uhttp"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+// requestTimeout matches the timeout of uhttp clients, which the proxy client replaces.
+const requestTimeout = 5 * time.Minute
+
+// transportConfig is how the client reaches Freshservice from networks that require an egress proxy,
+// inspect TLS with their own CA, or expect client certificates.
+type transportConfig struct {
+	proxyURL       string
+	caBundlePath   string
+	clientCertPath string
+	clientKeyPath  string
+}
+
+// WithProxyURL sends requests through an HTTP(S) proxy instead of the one from the HTTPS_PROXY environment.
+func (f *FreshServiceClient) WithProxyURL(proxyURL string) *FreshServiceClient {
+	f.transport.proxyURL = proxyURL
+	return f
+}
+
+// WithCABundle trusts the PEM certificates in caBundlePath in addition to the system roots,
+// e.g. the CA of a TLS-inspecting proxy.
+func (f *FreshServiceClient) WithCABundle(caBundlePath string) *FreshServiceClient {
+	f.transport.caBundlePath = caBundlePath
+	return f
+}
+
+// WithClientCertificate presents the PEM certificate and key for mutual TLS.
+func (f *FreshServiceClient) WithClientCertificate(certPath, keyPath string) *FreshServiceClient {
+	f.transport.clientCertPath = certPath
+	f.transport.clientKeyPath = keyPath
+	return f
+}
+
+// tlsConfig returns the TLS configuration for the CA bundle and client certificate, or nil to use the defaults.
+func (t transportConfig) tlsConfig() (*tls.Config, error) {
+	if t.caBundlePath == "" && t.clientCertPath == "" && t.clientKeyPath == "" {
+		return nil, nil
+	}
+
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if t.caBundlePath != "" {
+		pem, err := os.ReadFile(t.caBundlePath)
+		if err != nil {
+			return nil, fmt.Errorf("freshservice-connector: reading CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("freshservice-connector: no certificates found in CA bundle %s", t.caBundlePath)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if t.clientCertPath != "" || t.clientKeyPath != "" {
+		if t.clientCertPath == "" || t.clientKeyPath == "" {
+			return nil, fmt.Errorf("freshservice-connector: client certificate and key must be set together")
+		}
+		cert, err := tls.LoadX509KeyPair(t.clientCertPath, t.clientKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("freshservice-connector: loading client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
+
+// newHTTPClient builds the HTTP client used for Freshservice requests.
+//
+// uhttp transports always take their proxy from the environment, so a configured proxy gets a plain
+// http.Transport with the same TLS configuration instead.
+func (t transportConfig) newHTTPClient(ctx context.Context) (*http.Client, error) {
+	tlsConfig, err := t.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if t.proxyURL == "" {
+		options := []uhttp.Option{uhttp.WithLogger(true, ctxzap.Extract(ctx))}
+		if tlsConfig != nil {
+			options = append(options, uhttp.WithTLSClientConfig(tlsConfig))
+		}
+		return uhttp.NewClient(ctx, options...)
+	}
+
+	proxy, err := url.Parse(t.proxyURL)
+	if err != nil || proxy.Scheme == "" || proxy.Host == "" {
+		return nil, fmt.Errorf("freshservice-connector: invalid proxy URL %q", t.proxyURL)
+	}
+	ctxzap.Extract(ctx).Debug("freshservice-connector: using proxy", zap.String("proxy_host", proxy.Host))
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyURL(proxy)
+	transport.TLSClientConfig = tlsConfig
+
+	return &http.Client{
+		Timeout:   requestTimeout,
+		Transport: &loggingTransport{next: transport, logger: ctxzap.Extract(ctx)},
+	}, nil
+}
+
+// loggedResponseHeaders are the rate limit headers logged with each response, like uhttp transports do.
+var loggedResponseHeaders = []string{"X-RateLimit-Total", "X-RateLimit-Remaining", "X-RateLimit-Used-CurrentRequest", "Retry-After"}
+
+// loggingTransport logs requests the way uhttp transports do, for the proxy transport that replaces them.
+type loggingTransport struct {
+	next   http.RoundTripper
+	logger *zap.Logger
+}
+
+func (t *loggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+
+	fields := []zap.Field{
+		zap.String("http.method", req.Method),
+		zap.String("http.url_details.host", req.URL.Host),
+		zap.String("http.url_details.path", req.URL.Path),
+		zap.String("http.url_details.query", req.URL.RawQuery),
+		zap.Duration("duration", time.Since(start)),
+	}
+	if resp != nil {
+		headers := make(map[string][]string, len(loggedResponseHeaders))
+		for _, header := range loggedResponseHeaders {
+			if v := resp.Header.Values(header); len(v) > 0 {
+				headers[header] = v
+			}
+		}
+		fields = append(fields, zap.Int("http.status_code", resp.StatusCode), zap.Any("http.headers", headers))
+	}
+
+	switch {
+	case err != nil:
+		t.logger.Error("HTTP request failed", append(fields, zap.Error(err))...)
+	case resp.StatusCode >= http.StatusInternalServerError:
+		t.logger.Warn("HTTP request server error", fields...)
+	case resp.StatusCode >= http.StatusBadRequest:
+		t.logger.Debug("HTTP request client error", fields...)
+	default:
+		t.logger.Debug("HTTP request complete", fields...)
+	}
+	return resp, err
+}
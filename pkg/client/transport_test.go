@@ -0,0 +1,133 @@
+package client
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func writeRoles(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(RolesAPIData{Roles: []Roles{{ID: 1, Name: "Admin"}}})
+}
+
+func writePEM(t *testing.T, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "file.pem")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600))
+	return path
+}
+
+func newTLSTestClient(t *testing.T, server *httptest.Server, configure func(*FreshServiceClient)) (*FreshServiceClient, error) {
+	t.Helper()
+	fs := NewClient(nil).WithBearerToken("token").WithDomain("test").WithBaseURL(server.URL)
+	configure(fs)
+	return New(context.Background(), fs)
+}
+
+func TestCABundleIsTrusted(t *testing.T) {
+	t.Setenv("BATON_DISABLE_HTTP_CACHE", "true")
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeRoles(w)
+	}))
+	t.Cleanup(server.Close)
+
+	fs, err := newTLSTestClient(t, server, func(*FreshServiceClient) {})
+	require.NoError(t, err)
+	_, _, _, err = fs.ListRoles(context.Background(), PageOptions{})
+	require.Error(t, err)
+
+	caBundle := writePEM(t, "CERTIFICATE", server.Certificate().Raw)
+	fs, err = newTLSTestClient(t, server, func(fs *FreshServiceClient) { fs.WithCABundle(caBundle) })
+	require.NoError(t, err)
+	roles, _, _, err := fs.ListRoles(context.Background(), PageOptions{})
+	require.NoError(t, err)
+	require.Len(t, roles.Roles, 1)
+}
+
+func TestClientCertificateIsPresented(t *testing.T) {
+	t.Setenv("BATON_DISABLE_HTTP_CACHE", "true")
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Len(t, r.TLS.PeerCertificates, 1)
+		require.Equal(t, "baton-freshservice", r.TLS.PeerCertificates[0].Subject.CommonName)
+		writeRoles(w)
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	cert, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "baton-freshservice"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "baton-freshservice"}}, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	caBundle := writePEM(t, "CERTIFICATE", server.Certificate().Raw)
+	certPath := writePEM(t, "CERTIFICATE", cert)
+	keyPath := writePEM(t, "EC PRIVATE KEY", keyDER)
+	fs, err := newTLSTestClient(t, server, func(fs *FreshServiceClient) {
+		fs.WithCABundle(caBundle).WithClientCertificate(certPath, keyPath)
+	})
+	require.NoError(t, err)
+	_, _, _, err = fs.ListRoles(context.Background(), PageOptions{})
+	require.NoError(t, err)
+
+	_, err = newTLSTestClient(t, server, func(fs *FreshServiceClient) { fs.WithClientCertificate(certPath, "") })
+	require.Error(t, err)
+}
+
+func TestRequestsGoThroughProxy(t *testing.T) {
+	t.Setenv("BATON_DISABLE_HTTP_CACHE", "true")
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.Host+r.URL.Path)
+		writeRoles(w)
+	}))
+	t.Cleanup(proxy.Close)
+
+	core, logs := observer.New(zap.DebugLevel)
+	ctx := ctxzap.ToContext(context.Background(), zap.New(core))
+	fs, err := New(ctx, NewClient(nil).
+		WithBearerToken("token").
+		WithDomain("test").
+		WithBaseURL("http://test.freshservice.invalid/api/v2").
+		WithProxyURL(proxy.URL))
+	require.NoError(t, err)
+
+	roles, _, _, err := fs.ListRoles(context.Background(), PageOptions{})
+	require.NoError(t, err)
+	require.Len(t, roles.Roles, 1)
+	require.Equal(t, []string{"test.freshservice.invalid/api/v2/roles"}, proxied)
+
+	requests := logs.FilterMessage("HTTP request complete").All()
+	require.Len(t, requests, 1)
+	require.Equal(t, "/api/v2/roles", requests[0].ContextMap()["http.url_details.path"])
+
+	_, err = New(context.Background(), NewClient(nil).WithDomain("test").WithProxyURL("not a url"))
+	require.Error(t, err)
+}
@@ -7,7 +7,16 @@ type Freshservice struct {
 	ApiKey string `mapstructure:"api-key"`
 	Domain string `mapstructure:"domain"`
 	CategoryId string `mapstructure:"category-id"`
+	WorkspaceIds []string `mapstructure:"workspace-ids"`
 	BaseUrl string `mapstructure:"base-url"`
+	RateLimitPercent int `mapstructure:"rate-limit-percent"`
+	SyncStatePath string `mapstructure:"sync-state-path"`
+	FullSyncIntervalHours int `mapstructure:"full-sync-interval-hours"`
+	ProxyUrl string `mapstructure:"proxy-url"`
+	CaBundlePath string `mapstructure:"ca-bundle-path"`
+	ClientCertPath string `mapstructure:"client-cert-path"`
+	ClientKeyPath string `mapstructure:"client-key-path"`
+	ForgetDeletedRequesters bool `mapstructure:"forget-deleted-requesters"`
 	Ticketing bool `mapstructure:"ticketing"`
 }
 
@@ -26,18 +26,78 @@ var (
 		field.WithDisplayName("Category ID"),
 		field.WithDescription("The category id to filter service items to"),
 	)
+	workspaceIDsField = field.StringSliceField(
+		"workspace-ids",
+		field.WithDisplayName("Workspace IDs"),
+		field.WithDescription("Limit syncing and ticketing to these workspace IDs. All workspaces are used when empty"),
+	)
 	BaseURLField = field.StringField(
 		"base-url",
 		field.WithDescription("Override the Freshservice API URL (for testing)"),
 		field.WithHidden(true),
 		field.WithExportTarget(field.ExportTargetCLIOnly),
 	)
+	rateLimitPercentField = field.IntField(
+		"rate-limit-percent",
+		field.WithDisplayName("Rate limit percentage"),
+		field.WithDescription("The percentage (1-100) of the account's per-minute Freshservice API budget the connector may use"),
+		field.WithDefaultValue(100),
+		field.WithInt(func(r *field.IntRuler) {
+			r.Gte(1).Lte(100)
+		}),
+	)
+	syncStatePathField = field.StringField(
+		"sync-state-path",
+		field.WithDisplayName("Incremental sync state file"),
+		field.WithDescription("File used to keep agents and requesters between syncs so only updated users are fetched. Incremental sync is disabled when empty"),
+		field.WithExportTarget(field.ExportTargetCLIOnly),
+	)
+	fullSyncIntervalField = field.IntField(
+		"full-sync-interval-hours",
+		field.WithDisplayName("Full sync interval (hours)"),
+		field.WithDescription("How often an incremental sync falls back to listing every user, which picks up deleted users"),
+		field.WithDefaultValue(24),
+		field.WithInt(func(r *field.IntRuler) {
+			r.Gte(1)
+		}),
+	)
+	proxyURLField = field.StringField(
+		"proxy-url",
+		field.WithDisplayName("Proxy URL"),
+		field.WithDescription("HTTP(S) proxy to reach Freshservice through. The HTTPS_PROXY environment variable is used when empty"),
+	)
+	caBundlePathField = field.StringField(
+		"ca-bundle-path",
+		field.WithDisplayName("CA bundle path"),
+		field.WithDescription("PEM file with extra CA certificates to trust, e.g. the CA of a TLS-inspecting proxy"),
+		field.WithExportTarget(field.ExportTargetCLIOnly),
+	)
+	clientCertPathField = field.StringField(
+		"client-cert-path",
+		field.WithDisplayName("Client certificate path"),
+		field.WithDescription("PEM client certificate presented for mutual TLS"),
+		field.WithExportTarget(field.ExportTargetCLIOnly),
+	)
+	clientKeyPathField = field.StringField(
+		"client-key-path",
+		field.WithDisplayName("Client key path"),
+		field.WithDescription("PEM private key of the client certificate"),
+		field.WithExportTarget(field.ExportTargetCLIOnly),
+	)
+	forgetDeletedRequestersField = field.BoolField(
+		"forget-deleted-requesters",
+		field.WithDisplayName("Forget deleted requesters"),
+		field.WithDescription("Permanently delete requesters and their tickets when they are deleted, instead of deactivating them. Agents are always deactivated"),
+		field.WithDefaultValue(false),
+	)
 	externalTicketField = field.TicketingField.ExportAs(field.ExportTargetGUI)
-	configurationFields = []field.SchemaField{apiKeyField, domainField, categoryField, BaseURLField, externalTicketField}
+	configurationFields = []field.SchemaField{apiKeyField, domainField, categoryField, workspaceIDsField, BaseURLField, rateLimitPercentField, syncStatePathField, fullSyncIntervalField,
+		proxyURLField, caBundlePathField, clientCertPathField, clientKeyPathField, forgetDeletedRequestersField, externalTicketField}
 )
 
 var configRelations = []field.SchemaFieldRelationship{
 	field.FieldsDependentOn([]field.SchemaField{categoryField}, []field.SchemaField{field.TicketingField}),
+	field.FieldsRequiredTogether(clientCertPathField, clientKeyPathField),
 }
 
 //go:generate go run ./gen
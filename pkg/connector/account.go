@@ -0,0 +1,244 @@
+package connector
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Values of the account_type field of an account profile, which are the resource types of the created accounts.
+const (
+	accountTypeRequester = "requester"
+	accountTypeAgent     = "agent"
+)
+
+// agentAccountFields are the account profile fields that only apply to agents.
+var agentAccountFields = []string{"occasional", "roles", "group_ids"}
+
+// accountCreationSchema describes the profile of accounts created by the connector. IDs are strings since
+// Freshservice IDs don't fit the schema's 32-bit integers.
+var accountCreationSchema = &v2.ConnectorAccountCreationSchema{
+	FieldMap: map[string]*v2.ConnectorAccountCreationSchema_Field{
+		"account_type": {
+			DisplayName: "Account type",
+			Description: "Whether to create a requester (the default) or an agent.",
+			Placeholder: accountTypeRequester,
+			Order:       1,
+			Field:       &v2.ConnectorAccountCreationSchema_Field_StringField{StringField: &v2.ConnectorAccountCreationSchema_StringField{}},
+		},
+		"email": {
+			DisplayName: "Email",
+			Required:    true,
+			Description: "Primary email of the user, used to sign in.",
+			Placeholder: "jane.doe@example.com",
+			Order:       2,
+			Field:       &v2.ConnectorAccountCreationSchema_Field_StringField{StringField: &v2.ConnectorAccountCreationSchema_StringField{}},
+		},
+		"first_name": {
+			DisplayName: "First name",
+			Required:    true,
+			Placeholder: "Jane",
+			Order:       3,
+			Field:       &v2.ConnectorAccountCreationSchema_Field_StringField{StringField: &v2.ConnectorAccountCreationSchema_StringField{}},
+		},
+		"last_name": {
+			DisplayName: "Last name",
+			Placeholder: "Doe",
+			Order:       4,
+			Field:       &v2.ConnectorAccountCreationSchema_Field_StringField{StringField: &v2.ConnectorAccountCreationSchema_StringField{}},
+		},
+		"job_title": {
+			DisplayName: "Job title",
+			Placeholder: "Accountant",
+			Order:       5,
+			Field:       &v2.ConnectorAccountCreationSchema_Field_StringField{StringField: &v2.ConnectorAccountCreationSchema_StringField{}},
+		},
+		"department_ids": {
+			DisplayName: "Department IDs",
+			Description: "IDs of the departments, or companies in MSP mode, the user belongs to.",
+			Order:       6,
+			Field:       &v2.ConnectorAccountCreationSchema_Field_StringListField{StringListField: &v2.ConnectorAccountCreationSchema_StringListField{}},
+		},
+		"location_id": {
+			DisplayName: "Location ID",
+			Description: "ID of the location of the user.",
+			Order:       7,
+			Field:       &v2.ConnectorAccountCreationSchema_Field_StringField{StringField: &v2.ConnectorAccountCreationSchema_StringField{}},
+		},
+		"reporting_manager_id": {
+			DisplayName: "Reporting manager ID",
+			Description: "ID of the agent or requester the user reports to.",
+			Order:       8,
+			Field:       &v2.ConnectorAccountCreationSchema_Field_StringField{StringField: &v2.ConnectorAccountCreationSchema_StringField{}},
+		},
+		"occasional": {
+			DisplayName: "Occasional agent",
+			Description: "Agents only. Whether the agent uses day passes instead of a full-time license.",
+			Order:       9,
+			Field:       &v2.ConnectorAccountCreationSchema_Field_BoolField{BoolField: &v2.ConnectorAccountCreationSchema_BoolField{}},
+		},
+		"roles": {
+			DisplayName: "Roles",
+			Description: "Agents only, and required for them. Roles of the agent as role IDs, optionally followed by the " +
+				"assignment scope: entire_helpdesk, member_groups (the default) or specified_group_<group ID>.",
+			Placeholder: "10:entire_helpdesk",
+			Order:       10,
+			Field:       &v2.ConnectorAccountCreationSchema_Field_StringListField{StringListField: &v2.ConnectorAccountCreationSchema_StringListField{}},
+		},
+		"group_ids": {
+			DisplayName: "Group IDs",
+			Description: "Agents only. IDs of the agent groups the agent is a member of. Membership of approval-required " +
+				"groups is pending approval.",
+			Order: 11,
+			Field: &v2.ConnectorAccountCreationSchema_Field_StringListField{StringListField: &v2.ConnectorAccountCreationSchema_StringListField{}},
+		},
+	},
+}
+
+// accountEmail returns the email of an account, from its profile or else its primary email.
+func accountEmail(accountInfo *v2.AccountInfo) string {
+	if email := accountString(accountInfo.GetProfile(), "email"); email != "" {
+		return email
+	}
+	for _, email := range accountInfo.GetEmails() {
+		if email.GetIsPrimary() {
+			return email.GetAddress()
+		}
+	}
+	if emails := accountInfo.GetEmails(); len(emails) > 0 {
+		return emails[0].GetAddress()
+	}
+	return ""
+}
+
+// accountType returns the account_type of an account profile, which defaults to requester.
+func accountType(profile *structpb.Struct) (string, error) {
+	switch rv := strings.ToLower(accountString(profile, "account_type")); rv {
+	case "":
+		return accountTypeRequester, nil
+	case accountTypeRequester, accountTypeAgent:
+		return rv, nil
+	default:
+		return "", fmt.Errorf("freshservice-connector: invalid account_type %q, expected %s or %s", rv, accountTypeRequester, accountTypeAgent)
+	}
+}
+
+// accountFieldSet reports whether a field of an account profile is set to a value other than its zero value.
+func accountFieldSet(profile *structpb.Struct, key string) bool {
+	switch v := profile.GetFields()[key].GetKind().(type) {
+	case *structpb.Value_StringValue:
+		return strings.TrimSpace(v.StringValue) != ""
+	case *structpb.Value_BoolValue:
+		return v.BoolValue
+	case *structpb.Value_ListValue:
+		return len(v.ListValue.GetValues()) > 0
+	case *structpb.Value_NullValue, nil:
+		return false
+	default:
+		return true
+	}
+}
+
+// accountString returns a string field of an account profile, or an empty string when it isn't set.
+func accountString(profile *structpb.Struct, key string) string {
+	return strings.TrimSpace(profile.GetFields()[key].GetStringValue())
+}
+
+// accountID returns an ID field of an account profile, given as a string or a number, or nil when it isn't set.
+func accountID(profile *structpb.Struct, key string) (*int64, error) {
+	value, ok := profile.GetFields()[key]
+	if !ok {
+		return nil, nil
+	}
+	id, ok, err := parseAccountID(value)
+	if err != nil {
+		return nil, fmt.Errorf("freshservice-connector: invalid %s: %w", key, err)
+	}
+	if !ok {
+		return nil, nil
+	}
+	return &id, nil
+}
+
+// accountBool returns a boolean field of an account profile, given as a boolean or a string.
+func accountBool(profile *structpb.Struct, key string) (bool, error) {
+	switch v := profile.GetFields()[key].GetKind().(type) {
+	case *structpb.Value_BoolValue:
+		return v.BoolValue, nil
+	case *structpb.Value_StringValue:
+		if strings.TrimSpace(v.StringValue) == "" {
+			return false, nil
+		}
+		rv, err := strconv.ParseBool(strings.TrimSpace(v.StringValue))
+		if err != nil {
+			return false, fmt.Errorf("freshservice-connector: invalid %s: %w", key, err)
+		}
+		return rv, nil
+	case *structpb.Value_NullValue, nil:
+		return false, nil
+	default:
+		return false, fmt.Errorf("freshservice-connector: invalid %s: unexpected value %v", key, v)
+	}
+}
+
+// accountList returns the values of a list field of an account profile. A single comma-separated string is also
+// accepted.
+func accountList(profile *structpb.Struct, key string) []*structpb.Value {
+	value, ok := profile.GetFields()[key]
+	if !ok {
+		return nil
+	}
+
+	switch v := value.GetKind().(type) {
+	case *structpb.Value_ListValue:
+		return v.ListValue.GetValues()
+	case *structpb.Value_StringValue:
+		var rv []*structpb.Value
+		for _, item := range strings.Split(v.StringValue, ",") {
+			rv = append(rv, structpb.NewStringValue(item))
+		}
+		return rv
+	default:
+		return []*structpb.Value{value}
+	}
+}
+
+// accountIDs returns an ID list field of an account profile.
+func accountIDs(profile *structpb.Struct, key string) ([]int64, error) {
+	var rv []int64
+	for _, value := range accountList(profile, key) {
+		id, ok, err := parseAccountID(value)
+		if err != nil {
+			return nil, fmt.Errorf("freshservice-connector: invalid %s: %w", key, err)
+		}
+		if ok {
+			rv = append(rv, id)
+		}
+	}
+	return rv, nil
+}
+
+// parseAccountID parses an ID given as a string or a number, and returns false when it is empty.
+func parseAccountID(value *structpb.Value) (int64, bool, error) {
+	switch v := value.GetKind().(type) {
+	case *structpb.Value_NumberValue:
+		return int64(v.NumberValue), true, nil
+	case *structpb.Value_StringValue:
+		id := strings.TrimSpace(v.StringValue)
+		if id == "" {
+			return 0, false, nil
+		}
+		rv, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			return 0, false, err
+		}
+		return rv, true, nil
+	case *structpb.Value_NullValue, nil:
+		return 0, false, nil
+	default:
+		return 0, false, fmt.Errorf("unexpected value %v", value)
+	}
+}
@@ -2,8 +2,9 @@ package connector
 
 import (
 	"context"
+	"errors"
 	"fmt"
-
+	"slices"
 	"strconv"
 
 	"github.com/conductorone/baton-freshservice/pkg/client"
@@ -12,22 +13,35 @@ import (
 	"github.com/conductorone/baton-sdk/pkg/pagination"
 	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
 	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
 	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 type groupBuilder struct {
 	resourceType *v2.ResourceType
 	client       *client.FreshServiceClient
+	updates      *groupUpdates
 }
 
-const memberEntitlement = "member"
+const (
+	memberEntitlement            = "member"
+	observerEntitlement          = "observer"
+	leaderEntitlement            = "leader"
+	escalationContactEntitlement = "escalation_contact"
+	pendingMemberEntitlement     = "pending_member"
+)
+
+// groupEntitlements are the entitlements of an agent group, in the order their grants are returned.
+var groupEntitlements = []string{memberEntitlement, observerEntitlement, leaderEntitlement, escalationContactEntitlement}
 
 func (g *groupBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
 	return g.resourceType
 }
 
-// List returns all the groups from the database as resource objects.
+// List returns all the groups of a workspace as resource objects. On accounts without workspaces, the groups of the
+// account are listed without a parent instead.
 // Groups include a GroupTrait because they are the 'shape' of a standard group.
 func (g *groupBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
 	var rv []*v2.Resource
@@ -36,10 +50,24 @@ func (g *groupBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId
 		return nil, "", nil, err
 	}
 
+	var reqOpts []client.ReqOpt
+	if parentResourceID != nil {
+		reqOpts = append(reqOpts, client.WithWorkspaceID(parentResourceID.Resource))
+	} else if pageToken == 0 {
+		// Groups are synced as children of their workspace when the account has any.
+		hasWorkspaces, err := accountHasWorkspaces(ctx, g.client)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		if hasWorkspaces {
+			return nil, "", nil, nil
+		}
+	}
+
 	groups, nextPageToken, annotation, err := g.client.ListAgentGroups(ctx, client.PageOptions{
 		PerPage: pToken.Size,
 		Page:    pageToken,
-	})
+	}, reqOpts...)
 	if err != nil {
 		return nil, "", nil, err
 	}
@@ -51,7 +79,7 @@ func (g *groupBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId
 
 	for _, group := range groups.Groups {
 		groupCopy := group
-		ur, err := agentGroupResource(ctx, &groupCopy, nil)
+		ur, err := agentGroupResource(ctx, &groupCopy, parentResourceID)
 		if err != nil {
 			return nil, "", nil, err
 		}
@@ -66,6 +94,60 @@ func (g *groupBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId
 	return rv, nextPageToken, annotation, nil
 }
 
+// accountHasWorkspaces reports whether the account has workspaces. Accounts without them don't serve the workspaces
+// endpoint.
+func accountHasWorkspaces(ctx context.Context, c *client.FreshServiceClient) (bool, error) {
+	workspaces, _, _, err := c.ListWorkspaces(ctx, client.PageOptions{PerPage: 1})
+	if client.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return len(workspaces.Workspaces) > 0, nil
+}
+
+// syncedAgentGroups returns the agent groups synced by groupBuilder: the groups of the selected workspaces, or all the
+// groups of an account without workspaces.
+func syncedAgentGroups(ctx context.Context, c *client.FreshServiceClient) ([]client.AgentGroup, error) {
+	hasWorkspaces, err := accountHasWorkspaces(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	if !hasWorkspaces {
+		return collectAgentGroups(ctx, c)
+	}
+
+	var rv []client.AgentGroup
+	for page, err := range c.WorkspacePages(ctx) {
+		if err != nil {
+			return nil, err
+		}
+		for _, workspace := range page.Workspaces {
+			if !isWorkspaceSelected(c, workspace.ID) {
+				continue
+			}
+			groups, err := collectAgentGroups(ctx, c, client.WithWorkspaceID(strconv.FormatInt(workspace.ID, 10)))
+			if err != nil {
+				return nil, err
+			}
+			rv = append(rv, groups...)
+		}
+	}
+	return rv, nil
+}
+
+func collectAgentGroups(ctx context.Context, c *client.FreshServiceClient, reqOpts ...client.ReqOpt) ([]client.AgentGroup, error) {
+	var rv []client.AgentGroup
+	for page, err := range c.AgentGroupPages(ctx, reqOpts...) {
+		if err != nil {
+			return nil, err
+		}
+		rv = append(rv, page.Groups...)
+	}
+	return rv, nil
+}
+
 func (g *groupBuilder) Entitlements(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
 	var rv []*v2.Entitlement
 	options := []ent.EntitlementOption{
@@ -74,27 +156,89 @@ func (g *groupBuilder) Entitlements(ctx context.Context, resource *v2.Resource,
 		ent.WithDisplayName(fmt.Sprintf("%s Group %s", resource.DisplayName, memberEntitlement)),
 	}
 	rv = append(rv, ent.NewAssignmentEntitlement(resource, memberEntitlement, options...))
+	rv = append(rv, ent.NewAssignmentEntitlement(resource, observerEntitlement,
+		ent.WithGrantableTo(agentUserResourceType),
+		ent.WithDescription(fmt.Sprintf("Observer of %s group in FreshService, with read-only access to its tickets", resource.DisplayName)),
+		ent.WithDisplayName(fmt.Sprintf("%s Group %s", resource.DisplayName, observerEntitlement)),
+	))
+	rv = append(rv, ent.NewAssignmentEntitlement(resource, leaderEntitlement,
+		ent.WithGrantableTo(agentUserResourceType),
+		ent.WithDescription(fmt.Sprintf("Leader of %s group in FreshService", resource.DisplayName)),
+		ent.WithDisplayName(fmt.Sprintf("%s Group %s", resource.DisplayName, leaderEntitlement)),
+	))
+	rv = append(rv, ent.NewAssignmentEntitlement(resource, escalationContactEntitlement,
+		ent.WithGrantableTo(agentUserResourceType),
+		ent.WithDescription(fmt.Sprintf("Agent unassigned tickets of %s group in FreshService are escalated to", resource.DisplayName)),
+		ent.WithDisplayName(fmt.Sprintf("%s Group escalation contact", resource.DisplayName)),
+	))
+
+	if isApprovalRequired(resource) {
+		rv = append(rv, ent.NewAssignmentEntitlement(resource, pendingMemberEntitlement,
+			ent.WithGrantableTo(agentUserResourceType),
+			ent.WithDescription(fmt.Sprintf("Added to %s group in FreshService, pending approval by a group leader", resource.DisplayName)),
+			ent.WithDisplayName(fmt.Sprintf("%s Group pending member", resource.DisplayName)),
+		))
+	}
 
 	return rv, "", nil, nil
 }
 
+// isApprovalRequired reports whether members added to the group are pending until approved.
+func isApprovalRequired(resource *v2.Resource) bool {
+	trait, err := rs.GetGroupTrait(resource)
+	if err != nil {
+		return false
+	}
+	approvalRequired, ok := trait.GetProfile().GetFields()["approval_required"]
+	return ok && approvalRequired.GetBoolValue()
+}
+
+// pendingApprovalMetadata marks a membership that doesn't grant access until approved.
+func pendingApprovalMetadata() map[string]interface{} {
+	return map[string]interface{}{
+		"pending_approval": true,
+	}
+}
+
+// userPendingGroupGrants returns the pending memberships of an agent. They are only known from the agent.
+func userPendingGroupGrants(ctx context.Context, userID *v2.ResourceId, groupIDs []int64) ([]*v2.Grant, error) {
+	rv := make([]*v2.Grant, 0, len(groupIDs))
+	for _, groupID := range groupIDs {
+		group, err := agentGroupResource(ctx, &client.AgentGroup{ID: groupID, ApprovalRequired: true}, nil)
+		if err != nil {
+			return nil, err
+		}
+		rv = append(rv, grant.NewGrant(group, pendingMemberEntitlement, userID, grant.WithGrantMetadata(pendingApprovalMetadata())))
+	}
+	return rv, nil
+}
+
+// Grants returns the members, observers, leaders and escalation contact of a group. Pending members are granted by
+// the agents themselves.
 func (g *groupBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
-	var (
-		rv []*v2.Grant
-		gr *v2.Grant
-	)
+	var rv []*v2.Grant
 	groupDetail, annotation, err := g.client.GetAgentGroupDetail(ctx, resource.Id.Resource)
 	if err != nil {
 		return nil, "", nil, err
 	}
 
-	for _, agent := range groupDetail.Group.Members {
-		userId := &v2.ResourceId{
-			ResourceType: agentUserResourceType.Id,
-			Resource:     strconv.FormatInt(agent, 10),
+	agents := map[string][]int64{
+		memberEntitlement:   groupDetail.Group.Members,
+		observerEntitlement: groupDetail.Group.Observers,
+		leaderEntitlement:   groupDetail.Group.Leaders,
+	}
+	if groupDetail.Group.EscalateTo != nil {
+		agents[escalationContactEntitlement] = []int64{*groupDetail.Group.EscalateTo}
+	}
+
+	for _, entitlement := range groupEntitlements {
+		for _, agent := range agents[entitlement] {
+			userId := &v2.ResourceId{
+				ResourceType: agentUserResourceType.Id,
+				Resource:     strconv.FormatInt(agent, 10),
+			}
+			rv = append(rv, grant.NewGrant(resource, entitlement, userId))
 		}
-		gr = grant.NewGrant(resource, memberEntitlement, userId)
-		rv = append(rv, gr)
 	}
 
 	return rv, "", annotation, nil
@@ -113,25 +257,42 @@ func (g *groupBuilder) Grant(ctx context.Context, principal *v2.Resource, entitl
 
 	groupId := entitlement.Resource.Id.Resource
 	userId := principal.Id.Resource
-	groupDetail, annotation, err := g.client.GetAgentGroupDetail(ctx, groupId)
-	if err != nil {
-		return nil, err
-	}
-
 	user, err := strconv.ParseInt(userId, 10, 64)
 	if err != nil {
 		return nil, err
 	}
 
-	groupDetail.Group.Members = append(groupDetail.Group.Members, user)
+	slug := entitlementSlug(entitlement)
+	if slug == pendingMemberEntitlement {
+		return nil, fmt.Errorf("freshservice-connector: pending membership can't be granted, grant %s instead", memberEntitlement)
+	}
 
-	_, err = g.client.UpdateAgentGroupMembers(ctx,
-		groupId,
-		groupDetail.Group.Members,
-	)
+	group, annotation, noop, err := g.updates.apply(ctx, groupId, slug, user, true)
 	if err != nil {
 		return nil, err
 	}
+	if noop {
+		l.Info(
+			"freshservice-connector: agent already holds the group entitlement",
+			zap.String("entitlement_id", entitlement.Id),
+			zap.String("principal_id", userId),
+		)
+		annotation.Update(&v2.GrantAlreadyExists{})
+	}
+
+	// Members of approval-required groups only get access once a group leader approves them.
+	if slug == memberEntitlement && group.ApprovalRequired && !slices.Contains(group.Members, user) {
+		l.Info(
+			"freshservice-connector: group membership is pending approval",
+			zap.String("group_id", groupId),
+			zap.String("principal_id", userId),
+		)
+		md, err := structpb.NewStruct(pendingApprovalMetadata())
+		if err != nil {
+			return nil, err
+		}
+		annotation.Update(&v2.GrantMetadata{Metadata: md})
+	}
 
 	return annotation, nil
 }
@@ -152,39 +313,85 @@ func (g *groupBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations
 
 	userId := principal.Id.Resource
 	groupId := entitlement.Resource.Id.Resource
-	groupDetail, _, err := g.client.GetAgentGroupDetail(ctx, groupId)
+	user, err := strconv.ParseInt(userId, 10, 64)
 	if err != nil {
 		return nil, err
 	}
 
-	user, err := strconv.ParseInt(userId, 10, 64)
-	if err != nil {
+	// Freshservice has no way to withdraw a pending approval, which only a group leader can reject.
+	slug := entitlementSlug(entitlement)
+	if slug == pendingMemberEntitlement {
+		return g.revokePendingMembership(ctx, groupId, userId)
+	}
+
+	_, annotation, noop, err := g.updates.apply(ctx, groupId, slug, user, false)
+	switch {
+	case errors.Is(err, errPendingApproval):
+		// Agents pending approval hold the member entitlement, but removing them from the members leaves them pending.
+		return nil, pendingMembershipError(ctx, groupId, userId)
+	case client.IsNotFound(err):
+		// The group was deleted, taking the agent's entitlements with it.
+		l.Info(
+			"freshservice-connector: group not found, nothing to revoke",
+			zap.String("group_id", groupId),
+			zap.String("principal_id", userId),
+		)
+		return annotations.New(&v2.GrantAlreadyRevoked{}), nil
+	case err != nil:
 		return nil, err
 	}
+	if noop {
+		l.Info(
+			"freshservice-connector: agent no longer holds the group entitlement",
+			zap.String("entitlement_id", entitlement.Id),
+			zap.String("principal_id", userId),
+		)
+		annotation.Update(&v2.GrantAlreadyRevoked{})
+	}
 
-	members := []int64{}
-	for _, member := range groupDetail.Group.Members {
-		if member == user {
-			continue
-		}
+	return annotation, nil
+}
 
-		members = append(members, member)
+// revokePendingMembership fails for an agent whose membership is still pending approval, since it can't be
+// withdrawn, and reports the revoke as done once the approval was rejected or granted.
+func (g *groupBuilder) revokePendingMembership(ctx context.Context, groupId string, userId string) (annotations.Annotations, error) {
+	group, err := strconv.ParseInt(groupId, 10, 64)
+	if err != nil {
+		return nil, err
 	}
 
-	annotation, err := g.client.UpdateAgentGroupMembers(ctx,
-		groupId,
-		members,
-	)
-	if err != nil {
+	agent, _, err := g.client.GetAgentDetail(ctx, userId)
+	switch {
+	case client.IsNotFound(err):
+	case err != nil:
 		return nil, err
+	case slices.Contains(agent.Agent.MemberOfPendingApproval, group):
+		return nil, pendingMembershipError(ctx, groupId, userId)
 	}
 
-	return annotation, nil
+	ctxzap.Extract(ctx).Info(
+		"freshservice-connector: agent is no longer pending approval in the group",
+		zap.String("group_id", groupId),
+		zap.String("principal_id", userId),
+	)
+	return annotations.New(&v2.GrantAlreadyRevoked{}), nil
+}
+
+// pendingMembershipError is the error of revoking a membership that is pending approval.
+func pendingMembershipError(ctx context.Context, groupId string, userId string) error {
+	ctxzap.Extract(ctx).Warn(
+		"freshservice-connector: group membership pending approval can't be withdrawn",
+		zap.String("group_id", groupId),
+		zap.String("principal_id", userId),
+	)
+	return fmt.Errorf("freshservice-connector: the membership of agent %s in group %s is pending approval, which "+
+		"Freshservice can't withdraw; a group leader has to reject it", userId, groupId)
 }
 
 func newGroupBuilder(c *client.FreshServiceClient) *groupBuilder {
 	return &groupBuilder{
 		resourceType: agentGroupResourceType,
 		client:       c,
+		updates:      newGroupUpdates(c),
 	}
 }
@@ -2,17 +2,33 @@ package connector
 
 import (
 	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/conductorone/baton-freshservice/pkg/client"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/actions"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
 	"github.com/conductorone/baton-sdk/pkg/pagination"
-	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Values of the agent_type profile attribute. Freshservice state filters use the same names.
+const (
+	agentTypeFulltime   = "fulltime"
+	agentTypeOccasional = "occasional"
 )
 
 type agentUserBuilder struct {
 	resourceType *v2.ResourceType
 	client       *client.FreshServiceClient
+	// lister is set when incremental sync is enabled.
+	lister *incrementalLister[client.Agent]
 }
 
 func (u *agentUserBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
@@ -22,32 +38,52 @@ func (u *agentUserBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
 // List returns all the users from the database as resource objects.
 // Users include a UserTrait because they are the 'shape' of a standard user.
 func (u *agentUserBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
-	var rv []*v2.Resource
-	bag, pageToken, err := getToken(pToken, agentUserResourceType)
+	var (
+		users         []client.Agent
+		nextPageToken string
+		annotation    annotations.Annotations
+		err           error
+	)
+	if u.lister != nil {
+		users, nextPageToken, annotation, err = u.lister.list(ctx, pToken)
+	} else {
+		users, nextPageToken, annotation, err = u.listPage(ctx, pToken)
+	}
 	if err != nil {
 		return nil, "", nil, err
 	}
 
-	users, nextPageToken, annotation, err := u.client.ListAgentUsers(ctx, client.PageOptions{
-		PerPage: pToken.Size,
-		Page:    pageToken,
-	})
+	rv := make([]*v2.Resource, 0, len(users))
+	for _, user := range users {
+		userCopy := user
+		ur, err := agentResource(ctx, &userCopy, nil)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		rv = append(rv, ur)
+	}
+
+	return rv, nextPageToken, annotation, nil
+}
+
+func (u *agentUserBuilder) listPage(ctx context.Context, pToken *pagination.Token) ([]client.Agent, string, annotations.Annotations, error) {
+	bag := &pagination.Bag{}
+	err := bag.Unmarshal(pToken.Token)
 	if err != nil {
 		return nil, "", nil, err
 	}
+	if bag.Current() == nil {
+		bag.Push(pagination.PageState{ResourceTypeID: agentUserResourceType.Id})
+	}
 
-	err = bag.Next(nextPageToken)
+	users, nextPageToken, annotation, err := listAgentPage(ctx, u.client, pToken.Size, bag.PageToken())
 	if err != nil {
 		return nil, "", nil, err
 	}
 
-	for _, user := range users.Agents {
-		userCopy := user
-		ur, err := agentResource(ctx, &userCopy, nil)
-		if err != nil {
-			return nil, "", nil, err
-		}
-		rv = append(rv, ur)
+	err = bag.Next(nextPageToken)
+	if err != nil {
+		return nil, "", nil, err
 	}
 
 	nextPageToken, err = bag.Marshal()
@@ -55,7 +91,38 @@ func (u *agentUserBuilder) List(ctx context.Context, parentResourceID *v2.Resour
 		return nil, "", nil, err
 	}
 
-	return rv, nextPageToken, annotation, nil
+	return users, nextPageToken, annotation, nil
+}
+
+// inactiveAgentsPrefix marks the page tokens of deactivated agents, which /agents only returns when asked for.
+const inactiveAgentsPrefix = "inactive:"
+
+// listAgentPage lists the page of agents after token, going through the active agents first and then
+// the deactivated ones.
+func listAgentPage(ctx context.Context, c *client.FreshServiceClient, pageSize int, token string, reqOpts ...client.ReqOpt) ([]client.Agent, string, annotations.Annotations, error) {
+	pageToken, inactive := strings.CutPrefix(token, inactiveAgentsPrefix)
+	page, err := ConvertPageToken(pageToken)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	reqOpts = append([]client.ReqOpt{client.WithActive(!inactive)}, reqOpts...)
+	users, nextPage, annotation, err := c.ListAgentUsers(ctx, client.PageOptions{
+		PerPage: pageSize,
+		Page:    page,
+	}, reqOpts...)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	switch {
+	case inactive && nextPage != "":
+		nextPage = inactiveAgentsPrefix + nextPage
+	case !inactive && nextPage == "":
+		nextPage = inactiveAgentsPrefix
+	}
+
+	return users.Agents, nextPage, annotation, nil
 }
 
 // Entitlements always returns an empty slice for users.
@@ -63,7 +130,7 @@ func (u *agentUserBuilder) Entitlements(_ context.Context, resource *v2.Resource
 	return nil, "", nil, nil
 }
 
-// Grants always returns an empty slice for users since they don't have any entitlements.
+// Grants returns the roles with their assignment scope, the department memberships, the location and the pending group memberships of an agent.
 func (u *agentUserBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
 	var rv []*v2.Grant
 
@@ -75,24 +142,213 @@ func (u *agentUserBuilder) Grants(ctx context.Context, resource *v2.Resource, pT
 	}
 
 	for _, role := range agentDetail.Agent.Roles {
-		roleRes, err := roleResource(ctx, &client.Roles{
-			ID: role.RoleID,
-		}, nil)
+		roleGrants, err := userRoleGrants(ctx, resource.Id, role)
 		if err != nil {
 			return nil, "", nil, err
 		}
+		rv = append(rv, roleGrants...)
+	}
 
-		userId := &v2.ResourceId{
-			ResourceType: agentUserResourceType.Id,
-			Resource:     userId,
-		}
-		grant := grant.NewGrant(roleRes, assignedEntitlement, userId)
-		rv = append(rv, grant)
+	departmentGrants, err := userDepartmentGrants(ctx, resource.Id, agentDetail.Agent.DepartmentIDs)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	rv = append(rv, departmentGrants...)
+
+	locationGrants, err := userLocationGrants(ctx, resource.Id, agentDetail.Agent.LocationID)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	rv = append(rv, locationGrants...)
+
+	pendingGrants, err := userPendingGroupGrants(ctx, resource.Id, agentDetail.Agent.MemberOfPendingApproval)
+	if err != nil {
+		return nil, "", nil, err
 	}
+	rv = append(rv, pendingGrants...)
 
 	return rv, "", annotation, nil
 }
 
+// createAgentAccount creates an agent from the account profile described by accountCreationSchema, with its license
+// type, roles and group memberships. Agents sign in through Freshservice's activation email, so no credentials are
+// returned.
+func createAgentAccount(ctx context.Context, c *client.FreshServiceClient, accountInfo *v2.AccountInfo) (connectorbuilder.CreateAccountResponse, annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+	agent, err := newCreateAgent(accountInfo)
+	if err != nil {
+		l.Warn("freshservice-connector: invalid agent account", zap.Error(err))
+		return nil, nil, err
+	}
+
+	res, annotation, err := c.CreateAgent(ctx, agent)
+	if err != nil {
+		l.Warn(
+			"freshservice-connector: failed to create agent",
+			zap.String("email", agent.Email),
+			zap.Error(err),
+		)
+		return nil, nil, fmt.Errorf("freshservice-connector: failed to create agent %s: %w", agent.Email, err)
+	}
+
+	resource, err := agentResource(ctx, &res.Agent, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &v2.CreateAccountResponse_SuccessResult{
+		Resource:              resource,
+		IsCreateAccountResult: true,
+	}, annotation, nil
+}
+
+// newCreateAgent returns the agent described by an account profile.
+func newCreateAgent(accountInfo *v2.AccountInfo) (*client.CreateAgent, error) {
+	profile := accountInfo.GetProfile()
+	agent := &client.CreateAgent{
+		FirstName: accountString(profile, "first_name"),
+		LastName:  accountString(profile, "last_name"),
+		Email:     accountEmail(accountInfo),
+		JobTitle:  accountString(profile, "job_title"),
+	}
+	if agent.Email == "" {
+		return nil, fmt.Errorf("freshservice-connector: email is required to create an agent")
+	}
+	if agent.FirstName == "" {
+		return nil, fmt.Errorf("freshservice-connector: first_name is required to create an agent")
+	}
+
+	var err error
+	agent.Occasional, err = accountBool(profile, "occasional")
+	if err != nil {
+		return nil, err
+	}
+	agent.Roles, err = accountRoles(profile, "roles")
+	if err != nil {
+		return nil, err
+	}
+	if len(agent.Roles) == 0 {
+		return nil, fmt.Errorf("freshservice-connector: roles are required to create an agent")
+	}
+	agent.MemberOf, err = accountIDs(profile, "group_ids")
+	if err != nil {
+		return nil, err
+	}
+	agent.DepartmentIDs, err = accountIDs(profile, "department_ids")
+	if err != nil {
+		return nil, err
+	}
+	agent.LocationID, err = accountID(profile, "location_id")
+	if err != nil {
+		return nil, err
+	}
+	agent.ReportingManagerID, err = accountID(profile, "reporting_manager_id")
+	if err != nil {
+		return nil, err
+	}
+
+	return agent, nil
+}
+
+// accountRoles returns the roles of an account profile. Each role is a role ID, optionally followed by a colon and the
+// slug of one of the role's scope entitlements. Several specified_group entries of a role scope it to all of their
+// groups, and a role without a scope is scoped to the agent's groups, like roles granted without one.
+func accountRoles(profile *structpb.Struct, key string) ([]client.AgentRole, error) {
+	var rv []client.AgentRole
+	for _, value := range accountList(profile, key) {
+		entry := strings.TrimSpace(value.GetStringValue())
+		if entry == "" {
+			continue
+		}
+		roleID, slug, _ := strings.Cut(entry, ":")
+		id, err := strconv.ParseInt(strings.TrimSpace(roleID), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("freshservice-connector: invalid %s entry %q: %w", key, entry, err)
+		}
+		scope := ""
+		var groupID int64
+		if slug = strings.TrimSpace(slug); slug != "" {
+			scope, groupID, err = parseRoleSlug(slug)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		i := slices.IndexFunc(rv, func(role client.AgentRole) bool { return role.RoleID == id })
+		if i < 0 {
+			rv = append(rv, client.AgentRole{RoleID: id})
+			i = len(rv) - 1
+		}
+		role := &rv[i]
+		switch {
+		case scope == "":
+		case role.AssignmentScope != "" && role.AssignmentScope != scope:
+			return nil, fmt.Errorf("freshservice-connector: role %d has conflicting assignment scopes %s and %s", id, role.AssignmentScope, scope)
+		case scope == assignmentScopeSpecifiedGroups:
+			role.AssignmentScope = scope
+			if !slices.Contains(role.Groups, groupID) {
+				role.Groups = append(role.Groups, groupID)
+			}
+		default:
+			role.AssignmentScope = scope
+		}
+	}
+
+	for i := range rv {
+		if rv[i].AssignmentScope == "" {
+			rv[i].AssignmentScope = assignmentScopeMemberGroups
+		}
+	}
+	return rv, nil
+}
+
+// Delete deactivates the agent. Nothing is written when the agent is already inactive or gone.
+func (u *agentUserBuilder) Delete(ctx context.Context, resourceId *v2.ResourceId) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+	agentId := resourceId.Resource
+	agent, _, err := u.client.GetAgentDetail(ctx, agentId)
+	switch {
+	case client.IsNotFound(err):
+		l.Info("freshservice-connector: agent is already deleted", zap.String("agent_id", agentId))
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+	if !agent.Agent.Active {
+		l.Info("freshservice-connector: agent is already deactivated", zap.String("agent_id", agentId))
+		return nil, nil
+	}
+
+	annotation, err := u.client.DeactivateAgent(ctx, agentId)
+	if err != nil {
+		l.Warn(
+			"freshservice-connector: failed to deactivate agent",
+			zap.String("agent_id", agentId),
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("freshservice-connector: failed to deactivate agent %s: %w", agentId, err)
+	}
+
+	return annotation, nil
+}
+
+// ResourceActions registers the actions on agents.
+func (u *agentUserBuilder) ResourceActions(ctx context.Context, registry actions.ActionRegistry) error {
+	err := registry.Register(ctx, convertToRequesterSchema, u.convertToRequester)
+	if err != nil {
+		return err
+	}
+	err = registry.Register(ctx, userStatusSchema(agentUserResourceType, false), func(ctx context.Context, args *structpb.Struct) (*structpb.Struct, annotations.Annotations, error) {
+		return u.setStatus(ctx, args, false)
+	})
+	if err != nil {
+		return err
+	}
+	return registry.Register(ctx, userStatusSchema(agentUserResourceType, true), func(ctx context.Context, args *structpb.Struct) (*structpb.Struct, annotations.Annotations, error) {
+		return u.setStatus(ctx, args, true)
+	})
+}
+
 func newAgentUserBuilder(c *client.FreshServiceClient) *agentUserBuilder {
 	return &agentUserBuilder{
 		resourceType: agentUserResourceType,
@@ -0,0 +1,304 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/conductorone/baton-freshservice/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+const (
+	applicationUserEntitlement = "user"
+	licenseEntitlementPrefix   = "license_"
+)
+
+type applicationBuilder struct {
+	resourceType *v2.ResourceType
+	client       *client.FreshServiceClient
+	principals   *userPrincipals
+}
+
+func (a *applicationBuilder) ResourceType(_ context.Context) *v2.ResourceType {
+	return applicationResourceType
+}
+
+func (a *applicationBuilder) List(ctx context.Context, _ *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	var rv []*v2.Resource
+	bag, pageToken, err := getToken(pToken, applicationResourceType)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	// Applications are listed from the first page at the start of a sync; their users are resolved afresh.
+	if pageToken == 0 {
+		a.principals.reset()
+	}
+
+	applications, nextPageToken, annotation, err := a.client.ListApplications(ctx, client.PageOptions{
+		PerPage: pToken.Size,
+		Page:    pageToken,
+	})
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	err = bag.Next(nextPageToken)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	for _, application := range applications.Applications {
+		applicationCopy := application
+		ar, err := applicationResource(ctx, &applicationCopy)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		rv = append(rv, ar)
+	}
+
+	nextPageToken, err = bag.Marshal()
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return rv, nextPageToken, annotation, nil
+}
+
+// licenses returns all the licenses of an application.
+func (a *applicationBuilder) licenses(ctx context.Context, applicationID string) ([]client.ApplicationLicense, error) {
+	var rv []client.ApplicationLicense
+	for page, err := range a.client.ApplicationLicensePages(ctx, applicationID) {
+		if err != nil {
+			return nil, err
+		}
+		rv = append(rv, page.Licenses...)
+	}
+	return rv, nil
+}
+
+// Entitlements returns the user entitlement and one entitlement per license of an application. The licenses are only
+// looked up here, for the application being synced.
+func (a *applicationBuilder) Entitlements(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	licenses, err := a.licenses(ctx, resource.Id.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	rv := []*v2.Entitlement{
+		ent.NewAssignmentEntitlement(resource, applicationUserEntitlement,
+			ent.WithGrantableTo(agentUserResourceType, requesterResourceType),
+			ent.WithDescription(fmt.Sprintf("User of %s application in FreshService", resource.DisplayName)),
+			ent.WithDisplayName(fmt.Sprintf("%s Application %s", resource.DisplayName, applicationUserEntitlement)),
+		),
+	}
+	for _, license := range licenses {
+		name := license.Name
+		if name == "" {
+			name = strconv.FormatInt(license.ID, 10)
+		}
+		rv = append(rv, ent.NewAssignmentEntitlement(resource, licenseEntitlement(license.ID),
+			ent.WithGrantableTo(agentUserResourceType, requesterResourceType),
+			ent.WithDescription(fmt.Sprintf("Assigned %s license of %s application in FreshService, %d of %d purchased seats used",
+				name, resource.DisplayName, license.AllocatedCount, license.Quantity)),
+			ent.WithDisplayName(fmt.Sprintf("%s Application %s license", resource.DisplayName, name)),
+		))
+	}
+
+	return rv, "", nil, nil
+}
+
+// licenseEntitlement returns the slug of the entitlement of a license.
+func licenseEntitlement(licenseID int64) string {
+	return licenseEntitlementPrefix + strconv.FormatInt(licenseID, 10)
+}
+
+// Grants returns the users of an application, resolved to agents or requesters, and the licenses assigned to them.
+func (a *applicationBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	var rv []*v2.Grant
+	bag, pageToken, err := getToken(pToken, applicationResourceType)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	users, nextPageToken, annotation, err := a.client.ListApplicationUsers(ctx, resource.Id.Resource, client.PageOptions{
+		PerPage: pToken.Size,
+		Page:    pageToken,
+	})
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	err = bag.Next(nextPageToken)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	for _, user := range users.ApplicationUsers {
+		principal, err := a.principals.resourceID(ctx, user.UserID)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		rv = append(rv, grant.NewGrant(resource, applicationUserEntitlement, principal))
+		if user.LicenseID != nil {
+			rv = append(rv, grant.NewGrant(resource, licenseEntitlement(*user.LicenseID), principal))
+		}
+	}
+
+	nextPageToken, err = bag.Marshal()
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return rv, nextPageToken, annotation, nil
+}
+
+// Grant adds the user to the application. Granting a license assigns it to the user, adding the user first if
+// needed.
+func (a *applicationBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
+	err := checkApplicationProvisioning(ctx, principal)
+	if err != nil {
+		return nil, err
+	}
+
+	applicationID := entitlement.Resource.Id.Resource
+	userID, err := strconv.ParseInt(principal.Id.Resource, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	licenseID, isLicense, err := parseLicenseEntitlement(entitlement)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := a.applicationUser(ctx, applicationID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !isLicense {
+		if user != nil {
+			ctxzap.Extract(ctx).Info(
+				"freshservice-connector: user already uses the application",
+				zap.String("principal_id", principal.Id.Resource),
+				zap.String("application_id", applicationID),
+			)
+			return annotations.New(&v2.GrantAlreadyExists{}), nil
+		}
+		return a.client.AddApplicationUsers(ctx, applicationID, []client.ApplicationUser{{UserID: userID}})
+	}
+	if user == nil {
+		return a.client.AddApplicationUsers(ctx, applicationID, []client.ApplicationUser{{UserID: userID, LicenseID: &licenseID}})
+	}
+	if user.LicenseID != nil && *user.LicenseID == licenseID {
+		ctxzap.Extract(ctx).Info(
+			"freshservice-connector: license is already assigned to the user",
+			zap.String("principal_id", principal.Id.Resource),
+			zap.Int64("license_id", licenseID),
+		)
+		return annotations.New(&v2.GrantAlreadyExists{}), nil
+	}
+	return a.client.UpdateApplicationUsers(ctx, applicationID, []client.ApplicationUserLicense{{UserID: userID, LicenseID: &licenseID}})
+}
+
+// Revoke removes the user from the application. Revoking a license unassigns it, unless the user has since been
+// assigned another license, and keeps the user in the application.
+func (a *applicationBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	err := checkApplicationProvisioning(ctx, grant.Principal)
+	if err != nil {
+		return nil, err
+	}
+
+	applicationID := grant.Entitlement.Resource.Id.Resource
+	licenseID, isLicense, err := parseLicenseEntitlement(grant.Entitlement)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, err := strconv.ParseInt(grant.Principal.Id.Resource, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	user, err := a.applicationUser(ctx, applicationID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !isLicense {
+		if user == nil {
+			ctxzap.Extract(ctx).Info(
+				"freshservice-connector: user no longer uses the application",
+				zap.String("principal_id", grant.Principal.Id.Resource),
+				zap.String("application_id", applicationID),
+			)
+			return annotations.New(&v2.GrantAlreadyRevoked{}), nil
+		}
+		return a.client.DeleteApplicationUsers(ctx, applicationID, grant.Principal.Id.Resource)
+	}
+	if user == nil || user.LicenseID == nil || *user.LicenseID != licenseID {
+		ctxzap.Extract(ctx).Info(
+			"freshservice-connector: license is no longer assigned to the user",
+			zap.String("principal_id", grant.Principal.Id.Resource),
+			zap.Int64("license_id", licenseID),
+		)
+		return annotations.New(&v2.GrantAlreadyRevoked{}), nil
+	}
+	return a.client.UpdateApplicationUsers(ctx, applicationID, []client.ApplicationUserLicense{{UserID: userID}})
+}
+
+// parseLicenseEntitlement returns the license ID of a license entitlement, and false for the user entitlement.
+func parseLicenseEntitlement(entitlement *v2.Entitlement) (int64, bool, error) {
+	slug := entitlementSlug(entitlement)
+	licenseID, ok := strings.CutPrefix(slug, licenseEntitlementPrefix)
+	if !ok {
+		return 0, false, nil
+	}
+
+	id, err := strconv.ParseInt(licenseID, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("freshservice-connector: invalid license entitlement %q: %w", slug, err)
+	}
+	return id, true, nil
+}
+
+// applicationUser returns the application user with userID, or nil if the user doesn't use the application.
+func (a *applicationBuilder) applicationUser(ctx context.Context, applicationID string, userID int64) (*client.ApplicationUser, error) {
+	for page, err := range a.client.ApplicationUserPages(ctx, applicationID) {
+		if err != nil {
+			return nil, err
+		}
+		for _, user := range page.ApplicationUsers {
+			if user.UserID == userID {
+				return &user, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// checkApplicationProvisioning rejects principals that can't use an application.
+func checkApplicationProvisioning(ctx context.Context, principal *v2.Resource) error {
+	if principal.Id.ResourceType != agentUserResourceType.Id && principal.Id.ResourceType != requesterResourceType.Id {
+		ctxzap.Extract(ctx).Warn(
+			"freshservice-connector: only users can be granted application access",
+			zap.String("principal_type", principal.Id.ResourceType),
+			zap.String("principal_id", principal.Id.Resource),
+		)
+		return fmt.Errorf("freshservice-connector: only users can be granted application access")
+	}
+	return nil
+}
+
+func newApplicationBuilder(c *client.FreshServiceClient) *applicationBuilder {
+	return &applicationBuilder{
+		resourceType: applicationResourceType,
+		client:       c,
+		principals:   newUserPrincipals(c),
+	}
+}
@@ -0,0 +1,154 @@
+package connector
+
+import (
+	"testing"
+
+	"github.com/conductorone/baton-freshservice/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplicationUserGrants(t *testing.T) {
+	srv, c := newTestTenant(t)
+	srv.AddApplication(client.Application{ID: 60, Name: "Slack", ApplicationType: "saas"}, 1, 101, 102)
+	srv.AddApplication(client.Application{ID: 61, Name: "Zoom", ApplicationType: "saas"})
+	a := newApplicationBuilder(c)
+
+	applications := listAll(t, a, nil, 1)
+	require.Equal(t, []string{"60", "61"}, resourceIDs(applications))
+
+	var ids []string
+	token := &pagination.Token{Size: 2}
+	for {
+		grants, next, _, err := a.Grants(ctxTest, applications[0], token)
+		require.NoError(t, err)
+		for _, g := range grants {
+			ids = append(ids, g.Id)
+		}
+		if next == "" {
+			break
+		}
+		token = &pagination.Token{Size: 2, Token: next}
+	}
+	require.Equal(t, []string{
+		"application:60:user:agent:1",
+		"application:60:user:requester:101",
+		"application:60:user:requester:102",
+	}, ids)
+}
+
+func TestApplicationGrantAndRevoke(t *testing.T) {
+	srv, c := newTestTenant(t)
+	srv.AddApplication(client.Application{ID: 60, Name: "Slack"}, 1)
+	a := newApplicationBuilder(c)
+	slack, err := applicationResource(ctxTest, &client.Application{ID: 60, Name: "Slack"})
+	require.NoError(t, err)
+	requester, err := requesterUserResource(ctxTest, &client.Requesters{ID: 102}, nil)
+	require.NoError(t, err)
+	agent, err := agentResource(ctxTest, &client.Agent{ID: 1}, nil)
+	require.NoError(t, err)
+
+	_, err = a.Grant(ctxTest, requester, ent.NewAssignmentEntitlement(slack, applicationUserEntitlement))
+	require.NoError(t, err)
+	require.Equal(t, []int64{1, 102}, srv.ApplicationUsers(60))
+
+	_, err = a.Revoke(ctxTest, grant.NewGrant(slack, applicationUserEntitlement, agent))
+	require.NoError(t, err)
+	require.Equal(t, []int64{102}, srv.ApplicationUsers(60))
+
+	annos, err := a.Grant(ctxTest, requester, ent.NewAssignmentEntitlement(slack, applicationUserEntitlement))
+	require.NoError(t, err)
+	require.True(t, annos.Contains(&v2.GrantAlreadyExists{}))
+	annos, err = a.Revoke(ctxTest, grant.NewGrant(slack, applicationUserEntitlement, agent))
+	require.NoError(t, err)
+	require.True(t, annos.Contains(&v2.GrantAlreadyRevoked{}))
+	require.Equal(t, []int64{102}, srv.ApplicationUsers(60))
+
+	group, err := agentGroupResource(ctxTest, &client.AgentGroup{ID: 20}, nil)
+	require.NoError(t, err)
+	_, err = a.Grant(ctxTest, group, ent.NewAssignmentEntitlement(slack, applicationUserEntitlement))
+	require.Error(t, err)
+}
+
+func TestApplicationLicenseEntitlements(t *testing.T) {
+	srv, c := newTestTenant(t)
+	pro, contract := int64(70), int64(80)
+	srv.AddApplication(client.Application{ID: 60, Name: "Slack"})
+	srv.AddApplication(client.Application{ID: 61, Name: "Zoom"})
+	srv.AddApplicationLicense(60, client.ApplicationLicense{ID: pro, Name: "Pro", ContractID: &contract, Quantity: 10})
+	srv.AddApplicationUser(60, client.ApplicationUser{UserID: 1, LicenseID: &pro})
+	srv.AddApplicationUser(60, client.ApplicationUser{UserID: 101})
+	a := newApplicationBuilder(c)
+
+	applications := listAll(t, a, nil, 10)
+	require.Len(t, applications, 2)
+	// Licenses are only looked up for the application whose entitlements are synced.
+	require.Zero(t, countRequestsWithPrefix(srv, "GET /applications/60/licenses")+countRequestsWithPrefix(srv, "GET /applications/61/licenses"))
+
+	entitlements, _, _, err := a.Entitlements(ctxTest, applications[0], &pagination.Token{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"application:60:user", "application:60:license_70"}, []string{entitlements[0].Id, entitlements[1].Id})
+	require.Contains(t, entitlements[1].Description, "1 of 10 purchased seats used")
+	require.Equal(t, 1, countRequestsWithPrefix(srv, "GET /applications/60/licenses"))
+	require.Zero(t, countRequestsWithPrefix(srv, "GET /applications/61/licenses"))
+
+	grants, _, _, err := a.Grants(ctxTest, applications[0], &pagination.Token{})
+	require.NoError(t, err)
+	var ids []string
+	for _, g := range grants {
+		ids = append(ids, g.Id)
+	}
+	require.Equal(t, []string{
+		"application:60:user:agent:1",
+		"application:60:license_70:agent:1",
+		"application:60:user:requester:101",
+	}, ids)
+}
+
+func TestApplicationLicenseGrantAndRevoke(t *testing.T) {
+	srv, c := newTestTenant(t)
+	pro, basic := int64(70), int64(71)
+	srv.AddApplication(client.Application{ID: 60, Name: "Slack"}, 101)
+	srv.AddApplicationLicense(60, client.ApplicationLicense{ID: pro, Name: "Pro"})
+	srv.AddApplicationLicense(60, client.ApplicationLicense{ID: basic, Name: "Basic"})
+	a := newApplicationBuilder(c)
+	slack, err := applicationResource(ctxTest, &client.Application{ID: 60, Name: "Slack"})
+	require.NoError(t, err)
+	rae, err := requesterUserResource(ctxTest, &client.Requesters{ID: 101}, nil)
+	require.NoError(t, err)
+	sam, err := requesterUserResource(ctxTest, &client.Requesters{ID: 102}, nil)
+	require.NoError(t, err)
+
+	// Sam isn't using the application yet and is added with the license.
+	_, err = a.Grant(ctxTest, sam, ent.NewAssignmentEntitlement(slack, licenseEntitlement(pro)))
+	require.NoError(t, err)
+	user, ok := srv.ApplicationUser(60, 102)
+	require.True(t, ok)
+	require.Equal(t, pro, *user.LicenseID)
+
+	_, err = a.Grant(ctxTest, rae, ent.NewAssignmentEntitlement(slack, licenseEntitlement(basic)))
+	require.NoError(t, err)
+	user, _ = srv.ApplicationUser(60, 101)
+	require.Equal(t, basic, *user.LicenseID)
+
+	// Granting the license Rae already holds doesn't write.
+	annos, err := a.Grant(ctxTest, rae, ent.NewAssignmentEntitlement(slack, licenseEntitlement(basic)))
+	require.NoError(t, err)
+	require.True(t, annos.Contains(&v2.GrantAlreadyExists{}))
+	require.Equal(t, 1, countRequests(srv, "PUT /applications/60/users"))
+
+	// Revoking a license Rae no longer holds leaves hers alone.
+	_, err = a.Revoke(ctxTest, grant.NewGrant(slack, licenseEntitlement(pro), rae))
+	require.NoError(t, err)
+	user, _ = srv.ApplicationUser(60, 101)
+	require.Equal(t, basic, *user.LicenseID)
+
+	_, err = a.Revoke(ctxTest, grant.NewGrant(slack, licenseEntitlement(basic), rae))
+	require.NoError(t, err)
+	user, ok = srv.ApplicationUser(60, 101)
+	require.True(t, ok)
+	require.Nil(t, user.LicenseID)
+}
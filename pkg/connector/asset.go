@@ -0,0 +1,243 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/conductorone/baton-freshservice/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+const (
+	usedByEntitlement    = "used_by"
+	managedByEntitlement = "managed_by"
+)
+
+type assetBuilder struct {
+	resourceType *v2.ResourceType
+	client       *client.FreshServiceClient
+	principals   *userPrincipals
+}
+
+func (a *assetBuilder) ResourceType(_ context.Context) *v2.ResourceType {
+	return assetResourceType
+}
+
+// List returns the assets of an asset type.
+func (a *assetBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	// Assets are synced as children of their asset type.
+	if parentResourceID == nil {
+		return nil, "", nil, nil
+	}
+
+	var rv []*v2.Resource
+	bag, pageToken, err := getToken(pToken, assetResourceType)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	// Each sync resolves asset users and owners against a fresh list of agents.
+	if pageToken == 0 {
+		a.principals.reset()
+	}
+
+	assets, nextPageToken, annotation, err := a.client.ListAssets(ctx, client.PageOptions{
+		PerPage: pToken.Size,
+		Page:    pageToken,
+	}, client.WithAssetTypeID(parentResourceID.Resource))
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	err = bag.Next(nextPageToken)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	for _, asset := range assets.Assets {
+		assetCopy := asset
+		ar, err := assetResource(ctx, &assetCopy, parentResourceID)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		rv = append(rv, ar)
+	}
+
+	nextPageToken, err = bag.Marshal()
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return rv, nextPageToken, annotation, nil
+}
+
+func (a *assetBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return []*v2.Entitlement{
+		ent.NewAssignmentEntitlement(resource, usedByEntitlement,
+			ent.WithGrantableTo(agentUserResourceType, requesterResourceType),
+			ent.WithDescription(fmt.Sprintf("User of %s asset in FreshService", resource.DisplayName)),
+			ent.WithDisplayName(fmt.Sprintf("%s Asset used by", resource.DisplayName)),
+		),
+		ent.NewAssignmentEntitlement(resource, managedByEntitlement,
+			ent.WithGrantableTo(agentUserResourceType, agentGroupResourceType),
+			ent.WithDescription(fmt.Sprintf("Agent or agent group managing %s asset in FreshService", resource.DisplayName)),
+			ent.WithDisplayName(fmt.Sprintf("%s Asset managed by", resource.DisplayName)),
+		),
+	}, "", nil, nil
+}
+
+// Grants returns the user of an asset and the agent and agent group managing it, read from its profile.
+func (a *assetBuilder) Grants(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	var rv []*v2.Grant
+	profile := rs.GetProfile(resource)
+
+	if userID, ok := rs.GetProfileInt64Value(profile, "user_id"); ok {
+		principal, err := a.principals.resourceID(ctx, userID)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		rv = append(rv, grant.NewGrant(resource, usedByEntitlement, principal))
+	}
+	if agentID, ok := rs.GetProfileInt64Value(profile, "agent_id"); ok {
+		rv = append(rv, grant.NewGrant(resource, managedByEntitlement, &v2.ResourceId{
+			ResourceType: agentUserResourceType.Id,
+			Resource:     strconv.FormatInt(agentID, 10),
+		}))
+	}
+	if groupID, ok := rs.GetProfileInt64Value(profile, "group_id"); ok {
+		rv = append(rv, grant.NewGrant(resource, managedByEntitlement, &v2.ResourceId{
+			ResourceType: agentGroupResourceType.Id,
+			Resource:     strconv.FormatInt(groupID, 10),
+		}))
+	}
+
+	return rv, "", nil, nil
+}
+
+// Grant makes the principal the user, or the managing agent or agent group, of the asset, replacing the previous one.
+func (a *assetBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
+	err := checkAssetProvisioning(ctx, principal, entitlement)
+	if err != nil {
+		return nil, err
+	}
+
+	principalID, err := strconv.ParseInt(principal.Id.Resource, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := a.currentOwner(ctx, entitlement, principal.Id.ResourceType)
+	if err != nil {
+		return nil, err
+	}
+	if current != nil && *current == principalID {
+		ctxzap.Extract(ctx).Info(
+			"freshservice-connector: asset is already assigned to the principal",
+			zap.String("entitlement_id", entitlement.Id),
+			zap.String("principal_id", principal.Id.Resource),
+		)
+		return annotations.New(&v2.GrantAlreadyExists{}), nil
+	}
+
+	return a.updateOwner(ctx, entitlement, principal.Id.ResourceType, &principalID)
+}
+
+// Revoke clears the user, or the managing agent or agent group, of the asset, unless it has since been reassigned.
+func (a *assetBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	err := checkAssetProvisioning(ctx, grant.Principal, grant.Entitlement)
+	if err != nil {
+		return nil, err
+	}
+
+	principalID, err := strconv.ParseInt(grant.Principal.Id.Resource, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	// A deleted asset is no longer assigned to anyone.
+	current, err := a.currentOwner(ctx, grant.Entitlement, grant.Principal.Id.ResourceType)
+	if err != nil && !client.IsNotFound(err) {
+		return nil, err
+	}
+	if current == nil || *current != principalID {
+		ctxzap.Extract(ctx).Info(
+			"freshservice-connector: asset is no longer assigned to the principal",
+			zap.String("entitlement_id", grant.Entitlement.Id),
+			zap.String("principal_id", grant.Principal.Id.Resource),
+		)
+		return annotations.New(&v2.GrantAlreadyRevoked{}), nil
+	}
+
+	return a.updateOwner(ctx, grant.Entitlement, grant.Principal.Id.ResourceType, nil)
+}
+
+// checkAssetProvisioning rejects principals the entitlement can't be granted to: assets are used by agents or
+// requesters, and managed by agents or agent groups.
+func checkAssetProvisioning(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) error {
+	principalType := principal.Id.ResourceType
+	slug := entitlementSlug(entitlement)
+
+	var ok bool
+	switch slug {
+	case usedByEntitlement:
+		ok = principalType == agentUserResourceType.Id || principalType == requesterResourceType.Id
+	case managedByEntitlement:
+		ok = principalType == agentUserResourceType.Id || principalType == agentGroupResourceType.Id
+	}
+	if !ok {
+		ctxzap.Extract(ctx).Warn(
+			"freshservice-connector: asset entitlement can't be granted to principal",
+			zap.String("entitlement_id", entitlement.Id),
+			zap.String("principal_type", principalType),
+			zap.String("principal_id", principal.Id.Resource),
+		)
+		return fmt.Errorf("freshservice-connector: asset entitlement %q can't be granted to a %s", slug, principalType)
+	}
+
+	return nil
+}
+
+// currentOwner returns the asset field the entitlement and principal type map to.
+func (a *assetBuilder) currentOwner(ctx context.Context, entitlement *v2.Entitlement, principalType string) (*int64, error) {
+	asset, _, err := a.client.GetAsset(ctx, entitlement.Resource.Id.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case entitlementSlug(entitlement) == usedByEntitlement:
+		return asset.Asset.UserID, nil
+	case principalType == agentUserResourceType.Id:
+		return asset.Asset.AgentID, nil
+	default:
+		return asset.Asset.GroupID, nil
+	}
+}
+
+// updateOwner sets, or clears when id is nil, the asset field the entitlement and principal type map to.
+func (a *assetBuilder) updateOwner(ctx context.Context, entitlement *v2.Entitlement, principalType string, id *int64) (annotations.Annotations, error) {
+	displayID := entitlement.Resource.Id.Resource
+	switch {
+	case entitlementSlug(entitlement) == usedByEntitlement:
+		return a.client.UpdateAssetUser(ctx, displayID, id)
+	case principalType == agentUserResourceType.Id:
+		return a.client.UpdateAssetAgent(ctx, displayID, id)
+	default:
+		return a.client.UpdateAssetGroup(ctx, displayID, id)
+	}
+}
+
+func newAssetBuilder(c *client.FreshServiceClient) *assetBuilder {
+	return &assetBuilder{
+		resourceType: assetResourceType,
+		client:       c,
+		principals:   newUserPrincipals(c),
+	}
+}
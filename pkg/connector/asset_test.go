@@ -0,0 +1,107 @@
+package connector
+
+import (
+	"testing"
+
+	"github.com/conductorone/baton-freshservice/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssetsAreListedUnderAssetTypes(t *testing.T) {
+	srv, c := newTestTenant(t)
+	srv.AddAssetType(client.AssetType{ID: 90, Name: "Laptop"})
+	srv.AddAssetType(client.AssetType{ID: 91, Name: "Server"})
+	srv.AddAsset(client.Asset{ID: 1000, DisplayID: 1, Name: "MacBook", AssetTypeID: 90})
+	srv.AddAsset(client.Asset{ID: 1001, DisplayID: 2, Name: "db-01", AssetTypeID: 91})
+	srv.AddAsset(client.Asset{ID: 1002, DisplayID: 3, Name: "ThinkPad", AssetTypeID: 90})
+
+	assetTypes := listAll(t, newAssetTypeBuilder(c), nil, 1)
+	require.Equal(t, []string{"90", "91"}, resourceIDs(assetTypes))
+
+	a := newAssetBuilder(c)
+	require.Empty(t, listAll(t, a, nil, 10))
+	assets := listAll(t, a, assetTypes[0].Id, 1)
+	require.Equal(t, []string{"1", "3"}, resourceIDs(assets))
+	require.Equal(t, "90", assets[0].ParentResourceId.Resource)
+}
+
+func TestAssetGrants(t *testing.T) {
+	_, c := newTestTenant(t)
+	user, agent, group := int64(101), int64(2), int64(20)
+	asset, err := assetResource(ctxTest, &client.Asset{DisplayID: 1, Name: "MacBook", UserID: &user, AgentID: &agent, GroupID: &group},
+		&v2.ResourceId{ResourceType: assetTypeResourceType.Id, Resource: "90"})
+	require.NoError(t, err)
+
+	grants, _, _, err := newAssetBuilder(c).Grants(ctxTest, asset, &pagination.Token{})
+	require.NoError(t, err)
+	var ids []string
+	for _, g := range grants {
+		ids = append(ids, g.Id)
+	}
+	require.Equal(t, []string{
+		"asset:1:used_by:requester:101",
+		"asset:1:managed_by:agent:2",
+		"asset:1:managed_by:agent_group:20",
+	}, ids)
+}
+
+func TestAssetGrantAndRevoke(t *testing.T) {
+	srv, c := newTestTenant(t)
+	srv.AddAsset(client.Asset{ID: 1000, DisplayID: 1, Name: "MacBook", AssetTypeID: 90})
+	a := newAssetBuilder(c)
+	asset, err := assetResource(ctxTest, &client.Asset{DisplayID: 1, Name: "MacBook"}, nil)
+	require.NoError(t, err)
+	requester, err := requesterUserResource(ctxTest, &client.Requesters{ID: 102}, nil)
+	require.NoError(t, err)
+	agent, err := agentResource(ctxTest, &client.Agent{ID: 3}, nil)
+	require.NoError(t, err)
+	group, err := agentGroupResource(ctxTest, &client.AgentGroup{ID: 21}, nil)
+	require.NoError(t, err)
+
+	_, err = a.Grant(ctxTest, requester, ent.NewAssignmentEntitlement(asset, usedByEntitlement))
+	require.NoError(t, err)
+	_, err = a.Grant(ctxTest, agent, ent.NewAssignmentEntitlement(asset, managedByEntitlement))
+	require.NoError(t, err)
+	_, err = a.Grant(ctxTest, group, ent.NewAssignmentEntitlement(asset, managedByEntitlement))
+	require.NoError(t, err)
+	updated, _ := srv.Asset(1)
+	require.Equal(t, int64(102), *updated.UserID)
+	require.Equal(t, int64(3), *updated.AgentID)
+	require.Equal(t, int64(21), *updated.GroupID)
+
+	// Granting the current user again doesn't write.
+	puts := countRequests(srv, "PUT /assets/1")
+	annos, err := a.Grant(ctxTest, requester, ent.NewAssignmentEntitlement(asset, usedByEntitlement))
+	require.NoError(t, err)
+	require.True(t, annos.Contains(&v2.GrantAlreadyExists{}))
+	require.Equal(t, puts, countRequests(srv, "PUT /assets/1"))
+
+	// The asset isn't used by agent 3, so revoking that leaves the user alone.
+	_, err = a.Revoke(ctxTest, grant.NewGrant(asset, usedByEntitlement, agent))
+	require.NoError(t, err)
+	updated, _ = srv.Asset(1)
+	require.Equal(t, int64(102), *updated.UserID)
+
+	_, err = a.Revoke(ctxTest, grant.NewGrant(asset, usedByEntitlement, requester))
+	require.NoError(t, err)
+	_, err = a.Revoke(ctxTest, grant.NewGrant(asset, managedByEntitlement, group))
+	require.NoError(t, err)
+	updated, _ = srv.Asset(1)
+	require.Nil(t, updated.UserID)
+	require.Nil(t, updated.GroupID)
+	require.Equal(t, int64(3), *updated.AgentID)
+
+	// Revoking from an asset that has been deleted is a no-op.
+	deleted, err := assetResource(ctxTest, &client.Asset{DisplayID: 2, Name: "Deleted"}, nil)
+	require.NoError(t, err)
+	annos, err = a.Revoke(ctxTest, grant.NewGrant(deleted, usedByEntitlement, requester))
+	require.NoError(t, err)
+	require.True(t, annos.Contains(&v2.GrantAlreadyRevoked{}))
+
+	_, err = a.Grant(ctxTest, requester, ent.NewAssignmentEntitlement(asset, managedByEntitlement))
+	require.Error(t, err)
+}
@@ -0,0 +1,74 @@
+package connector
+
+import (
+	"context"
+
+	"github.com/conductorone/baton-freshservice/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+)
+
+type assetTypeBuilder struct {
+	resourceType *v2.ResourceType
+	client       *client.FreshServiceClient
+}
+
+func (a *assetTypeBuilder) ResourceType(_ context.Context) *v2.ResourceType {
+	return assetTypeResourceType
+}
+
+// List returns all the asset types. Asset types are the parents of assets.
+func (a *assetTypeBuilder) List(ctx context.Context, _ *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	var rv []*v2.Resource
+	bag, pageToken, err := getToken(pToken, assetTypeResourceType)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	assetTypes, nextPageToken, annotation, err := a.client.ListAssetTypes(ctx, client.PageOptions{
+		PerPage: pToken.Size,
+		Page:    pageToken,
+	})
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	err = bag.Next(nextPageToken)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	for _, assetType := range assetTypes.AssetTypes {
+		assetTypeCopy := assetType
+		ar, err := assetTypeResource(ctx, &assetTypeCopy)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		rv = append(rv, ar)
+	}
+
+	nextPageToken, err = bag.Marshal()
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return rv, nextPageToken, annotation, nil
+}
+
+// Entitlements always returns an empty slice for asset types.
+func (a *assetTypeBuilder) Entitlements(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// Grants always returns an empty slice for asset types since they don't have any entitlements.
+func (a *assetTypeBuilder) Grants(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+func newAssetTypeBuilder(c *client.FreshServiceClient) *assetTypeBuilder {
+	return &assetTypeBuilder{
+		resourceType: assetTypeResourceType,
+		client:       c,
+	}
+}
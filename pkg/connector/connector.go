@@ -3,25 +3,80 @@ package connector
 import (
 	"context"
 	"io"
+	"time"
 
 	"github.com/conductorone/baton-freshservice/pkg/client"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 type Connector struct {
-	client *client.FreshServiceClient
+	client      *client.FreshServiceClient
+	incremental *incrementalStore
+
+	statePath        string
+	fullSyncInterval time.Duration
+
+	forgetDeletedRequesters bool
+}
+
+// Option configures optional connector behaviour.
+type Option func(*Connector)
+
+// WithIncrementalSync keeps agents and requesters in the state file at statePath, so that syncs only fetch the users
+// updated since the previous sync. A full sync still runs every fullSyncInterval to pick up deleted users.
+func WithIncrementalSync(statePath string, fullSyncInterval time.Duration) Option {
+	return func(c *Connector) {
+		c.statePath = statePath
+		c.fullSyncInterval = fullSyncInterval
+	}
+}
+
+// Ways agents and requesters are deleted. Deactivated users keep their tickets and can be reactivated, while forgotten
+// requesters are deleted along with their tickets for good.
+const (
+	deletionModeDeactivate = "deactivate"
+	deletionModeForget     = "forget"
+)
+
+// WithForgetDeletedRequesters permanently deletes requesters when they are deleted, instead of deactivating them.
+func WithForgetDeletedRequesters(forget bool) Option {
+	return func(c *Connector) {
+		c.forgetDeletedRequesters = forget
+	}
 }
 
 // ResourceSyncers returns a ResourceSyncer for each resource type that should be synced from the upstream service.
 func (d *Connector) ResourceSyncers(ctx context.Context) []connectorbuilder.ResourceSyncer {
+	agents := newAgentUserBuilder(d.client)
+	requesters := newRequesterUserBuilder(d.client)
+	requesters.forget = d.forgetDeletedRequesters
+	if d.incremental != nil {
+		agents.lister = newAgentLister(d.incremental, d.client)
+		requesters.lister = newRequesterLister(d.incremental, d.client)
+	}
+
+	// Departments, applications and assets share the agents they resolve their users against.
+	departments := newDepartmentBuilder(d.client)
+	applications := newApplicationBuilder(d.client)
+	assets := newAssetBuilder(d.client)
+	applications.principals = departments.principals
+	assets.principals = departments.principals
+
 	return []connectorbuilder.ResourceSyncer{
-		newAgentUserBuilder(d.client),
-		newRequesterUserBuilder(d.client),
+		agents,
+		requesters,
+		newWorkspaceBuilder(d.client),
 		newGroupBuilder(d.client),
 		newRoleBuilder(d.client),
 		newRequesterGroupBuilder(d.client),
+		departments,
+		newLocationBuilder(d.client),
+		applications,
+		newAssetTypeBuilder(d.client),
+		assets,
 	}
 }
 
@@ -31,11 +86,26 @@ func (d *Connector) Asset(ctx context.Context, asset *v2.AssetRef) (string, io.R
 	return "", nil, nil
 }
 
-// Metadata returns metadata about the connector.
+// Metadata returns metadata about the connector. Its profile tells how agents and requesters are deleted, so that
+// offboarding can tell a reversible deactivation from a permanent deletion.
 func (d *Connector) Metadata(ctx context.Context) (*v2.ConnectorMetadata, error) {
+	requesterDeletionMode := deletionModeDeactivate
+	if d.forgetDeletedRequesters {
+		requesterDeletionMode = deletionModeForget
+	}
+	profile, err := structpb.NewStruct(map[string]interface{}{
+		"agent_deletion_mode":     deletionModeDeactivate,
+		"requester_deletion_mode": requesterDeletionMode,
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	return &v2.ConnectorMetadata{
-		DisplayName: "FreshService Connector",
-		Description: "Connector syncing users, groups, roles and requester groups from FreshService.",
+		DisplayName:           "FreshService Connector",
+		Description:           "Connector syncing users, workspaces, groups, roles, requester groups, departments, locations, applications and assets from FreshService.",
+		Profile:               profile,
+		AccountCreationSchema: accountCreationSchema,
 	}, nil
 }
 
@@ -46,7 +116,7 @@ func (d *Connector) Validate(ctx context.Context) (annotations.Annotations, erro
 }
 
 // New returns a new instance of the connector.
-func New(ctx context.Context, apiKey, domain string, freshServiceClient *client.FreshServiceClient) (*Connector, error) {
+func New(ctx context.Context, apiKey, domain string, freshServiceClient *client.FreshServiceClient, opts ...Option) (*Connector, error) {
 	var err error
 	if apiKey != "" && domain != "" {
 		freshServiceClient, err = client.New(ctx, freshServiceClient)
@@ -55,7 +125,19 @@ func New(ctx context.Context, apiKey, domain string, freshServiceClient *client.
 		}
 	}
 
-	return &Connector{
+	c := &Connector{
 		client: freshServiceClient,
-	}, nil
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.statePath != "" {
+		c.incremental, err = newIncrementalStore(c.statePath, c.fullSyncInterval)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
 }
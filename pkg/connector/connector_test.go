@@ -0,0 +1,987 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/conductorone/baton-freshservice/pkg/client"
+	"github.com/conductorone/baton-freshservice/pkg/client/clienttest"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/actions"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types"
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// newTestTenant starts a fake Freshservice seeded with a small tenant and returns it with a client pointed at it.
+func newTestTenant(t *testing.T) (*clienttest.Server, *client.FreshServiceClient) {
+	t.Helper()
+	srv := clienttest.NewServer(t)
+
+	srv.AddWorkspace(client.Workspace{ID: 2, Name: "IT", State: "active", Primary: true})
+	srv.AddWorkspace(client.Workspace{ID: 3, Name: "HR", State: "active"})
+	srv.AddAgent(client.Agent{ID: 1, Active: true, FirstName: "Ada", LastName: "Admin", Email: "ada@example.com",
+		Roles: []client.AgentRole{{RoleID: 10, AssignmentScope: "entire_helpdesk"}}})
+	srv.AddAgent(client.Agent{ID: 2, Active: true, FirstName: "Bo", LastName: "Agent", Email: "bo@example.com"})
+	srv.AddAgent(client.Agent{ID: 3, Active: true, FirstName: "Cy", LastName: "Agent", Email: "cy@example.com"})
+	srv.AddRequester(client.Requesters{ID: 101, Active: true, FirstName: "Rae", PrimaryEmail: "rae@example.com"})
+	srv.AddRequester(client.Requesters{ID: 102, Active: true, FirstName: "Sam", PrimaryEmail: "sam@example.com"})
+	srv.AddRequester(client.Requesters{ID: 103, Active: false, FirstName: "Tia", PrimaryEmail: "tia@example.com"})
+	srv.AddGroup(client.AgentGroup{ID: 20, Name: "Service Desk", WorkspaceID: 2, Members: []int64{1}})
+	srv.AddGroup(client.AgentGroup{ID: 21, Name: "Network", WorkspaceID: 2})
+	srv.AddGroup(client.AgentGroup{ID: 22, Name: "People Ops", WorkspaceID: 3})
+	srv.AddRole(client.Roles{ID: 10, Name: "Account Admin"})
+	srv.AddRole(client.Roles{ID: 11, Name: "IT Ops Agent"})
+	srv.AddRequesterGroup(client.RequesterGroup{ID: 30, Name: "HR Team", Type: "manual"}, 101)
+
+	return srv, srv.NewClient(t)
+}
+
+func listAll(t *testing.T, syncer connectorbuilder.ResourceSyncer, parent *v2.ResourceId, pageSize int) []*v2.Resource {
+	t.Helper()
+	var rv []*v2.Resource
+	token := &pagination.Token{Size: pageSize}
+	for {
+		resources, next, _, err := syncer.List(context.Background(), parent, token)
+		require.NoError(t, err)
+		rv = append(rv, resources...)
+		if next == "" {
+			return rv
+		}
+		token = &pagination.Token{Size: pageSize, Token: next}
+	}
+}
+
+func resourceIDs(resources []*v2.Resource) []string {
+	ids := make([]string, 0, len(resources))
+	for _, r := range resources {
+		ids = append(ids, r.Id.Resource)
+	}
+	return ids
+}
+
+func grantPrincipalIDs(grants []*v2.Grant) []string {
+	ids := make([]string, 0, len(grants))
+	for _, g := range grants {
+		ids = append(ids, g.Principal.Id.Resource)
+	}
+	return ids
+}
+
+func TestBuildersListFollowPagination(t *testing.T) {
+	_, c := newTestTenant(t)
+
+	require.Equal(t, []string{"1", "2", "3"}, resourceIDs(listAll(t, newAgentUserBuilder(c), nil, 2)))
+	require.Equal(t, []string{"101", "102", "103"}, resourceIDs(listAll(t, newRequesterUserBuilder(c), nil, 2)))
+	require.Equal(t, []string{"2", "3"}, resourceIDs(listAll(t, newWorkspaceBuilder(c), nil, 1)))
+	require.Equal(t, []string{"10", "11"}, resourceIDs(listAll(t, newRoleBuilder(c), nil, 1)))
+	require.Equal(t, []string{"30"}, resourceIDs(listAll(t, newRequesterGroupBuilder(c), nil, 1)))
+}
+
+func TestAgentsIncludeDeactivatedAndOccasional(t *testing.T) {
+	srv, c := newTestTenant(t)
+	srv.AddAgent(client.Agent{ID: 4, Active: false, FirstName: "Di", Email: "di@example.com"})
+	srv.AddAgent(client.Agent{ID: 5, Active: true, Occasional: true, FirstName: "Ed", Email: "ed@example.com"})
+
+	agents := listAll(t, newAgentUserBuilder(c), nil, 2)
+	require.Equal(t, []string{"1", "2", "3", "5", "4"}, resourceIDs(agents))
+
+	byID := make(map[string]*v2.Resource)
+	for _, agent := range agents {
+		byID[agent.Id.Resource] = agent
+	}
+	for id, want := range map[string]struct {
+		status    v2.UserTrait_Status_Status
+		agentType string
+	}{
+		"1": {v2.UserTrait_Status_STATUS_ENABLED, "fulltime"},
+		"4": {v2.UserTrait_Status_STATUS_DISABLED, "fulltime"},
+		"5": {v2.UserTrait_Status_STATUS_ENABLED, "occasional"},
+	} {
+		trait, err := rs.GetUserTrait(byID[id])
+		require.NoError(t, err)
+		require.Equal(t, want.status, trait.Status.Status, id)
+		require.Equal(t, want.agentType, trait.Profile.AsMap()["agent_type"], id)
+	}
+}
+
+func TestGroupsAreListedPerWorkspace(t *testing.T) {
+	_, c := newTestTenant(t)
+	g := newGroupBuilder(c)
+
+	require.Empty(t, listAll(t, g, nil, 1))
+
+	workspace := &v2.ResourceId{ResourceType: workspaceResourceType.Id, Resource: "2"}
+	groups := listAll(t, g, workspace, 1)
+	require.Equal(t, []string{"20", "21"}, resourceIDs(groups))
+	for _, group := range groups {
+		require.Equal(t, workspace, group.ParentResourceId)
+	}
+	require.Equal(t, []string{"22"}, resourceIDs(listAll(t, g, &v2.ResourceId{ResourceType: workspaceResourceType.Id, Resource: "3"}, 1)))
+}
+
+func TestGroupsAreListedWithoutWorkspaces(t *testing.T) {
+	srv := clienttest.NewServer(t)
+	srv.AddGroup(client.AgentGroup{ID: 20, Name: "Service Desk"})
+	srv.AddGroup(client.AgentGroup{ID: 21, Name: "Network"})
+
+	groups := listAll(t, newGroupBuilder(srv.NewClient(t)), nil, 1)
+	require.Equal(t, []string{"20", "21"}, resourceIDs(groups))
+	for _, group := range groups {
+		require.Nil(t, group.ParentResourceId)
+	}
+}
+
+func TestWorkspacesAreRestrictedToConfiguredIDs(t *testing.T) {
+	_, c := newTestTenant(t)
+	c.WithWorkspaceIDs([]string{"3"})
+
+	workspaces := listAll(t, newWorkspaceBuilder(c), nil, 10)
+	require.Equal(t, []string{"3"}, resourceIDs(workspaces))
+	require.NotNil(t, workspaces[0].Annotations)
+}
+
+func TestAgentUserGrantsRoles(t *testing.T) {
+	_, c := newTestTenant(t)
+	agent, err := agentResource(ctxTest, &client.Agent{ID: 1}, nil)
+	require.NoError(t, err)
+
+	grants, next, _, err := newAgentUserBuilder(c).Grants(ctxTest, agent, &pagination.Token{})
+	require.NoError(t, err)
+	require.Empty(t, next)
+	require.Len(t, grants, 2)
+	require.Equal(t, "role:10:assigned:agent:1", grants[0].Id)
+	require.Equal(t, "role:10:entire_helpdesk:agent:1", grants[1].Id)
+
+	metadata := &v2.GrantMetadata{}
+	annos := annotations.Annotations(grants[0].Annotations)
+	ok, err := annos.Pick(metadata)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "entire_helpdesk", metadata.Metadata.AsMap()["assignment_scope"])
+}
+
+func TestAgentUserGrantsRoleSpecifiedGroups(t *testing.T) {
+	srv, c := newTestTenant(t)
+	srv.AddAgent(client.Agent{ID: 4, Active: true, FirstName: "Di", Email: "di@example.com",
+		Roles: []client.AgentRole{{RoleID: 11, AssignmentScope: "specified_groups", Groups: []int64{20, 21}}}})
+	agent, err := agentResource(ctxTest, &client.Agent{ID: 4}, nil)
+	require.NoError(t, err)
+
+	grants, _, _, err := newAgentUserBuilder(c).Grants(ctxTest, agent, &pagination.Token{})
+	require.NoError(t, err)
+	var ids []string
+	for _, g := range grants {
+		ids = append(ids, g.Id)
+	}
+	require.Equal(t, []string{
+		"role:11:assigned:agent:4",
+		"role:11:specified_group_20:agent:4",
+		"role:11:specified_group_21:agent:4",
+	}, ids)
+
+	metadata := &v2.GrantMetadata{}
+	annos := annotations.Annotations(grants[0].Annotations)
+	_, err = annos.Pick(metadata)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{float64(20), float64(21)}, metadata.Metadata.AsMap()["group_ids"])
+}
+
+func TestGroupGrantAndRevoke(t *testing.T) {
+	srv, c := newTestTenant(t)
+	g := newGroupBuilder(c)
+	group, err := agentGroupResource(ctxTest, &client.AgentGroup{ID: 20, Name: "Service Desk"}, nil)
+	require.NoError(t, err)
+	agent, err := agentResource(ctxTest, &client.Agent{ID: 2}, nil)
+	require.NoError(t, err)
+
+	_, err = g.Grant(ctxTest, agent, ent.NewAssignmentEntitlement(group, memberEntitlement))
+	require.NoError(t, err)
+	require.Equal(t, []int64{1, 2}, srv.GroupMembers(20))
+
+	grants, _, _, err := g.Grants(ctxTest, group, &pagination.Token{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"1", "2"}, grantPrincipalIDs(grants))
+
+	_, err = g.Revoke(ctxTest, grant.NewGrant(group, memberEntitlement, agent))
+	require.NoError(t, err)
+	require.Equal(t, []int64{1}, srv.GroupMembers(20))
+}
+
+func TestGroupObserverLeaderAndEscalationContact(t *testing.T) {
+	srv, c := newTestTenant(t)
+	escalateTo := int64(1)
+	srv.AddGroup(client.AgentGroup{ID: 23, Name: "Security", WorkspaceID: 2, Members: []int64{1},
+		Observers: []int64{2}, Leaders: []int64{1}, EscalateTo: &escalateTo})
+	g := newGroupBuilder(c)
+	group, err := agentGroupResource(ctxTest, &client.AgentGroup{ID: 23, Name: "Security"}, nil)
+	require.NoError(t, err)
+
+	entitlements, _, _, err := g.Entitlements(ctxTest, group, &pagination.Token{})
+	require.NoError(t, err)
+	require.Len(t, entitlements, 4)
+
+	grants, _, _, err := g.Grants(ctxTest, group, &pagination.Token{})
+	require.NoError(t, err)
+	var ids []string
+	for _, gr := range grants {
+		ids = append(ids, gr.Id)
+	}
+	require.Equal(t, []string{
+		"agent_group:23:member:agent:1",
+		"agent_group:23:observer:agent:2",
+		"agent_group:23:leader:agent:1",
+		"agent_group:23:escalation_contact:agent:1",
+	}, ids)
+
+	agent, err := agentResource(ctxTest, &client.Agent{ID: 3}, nil)
+	require.NoError(t, err)
+	for _, entitlement := range []string{observerEntitlement, leaderEntitlement, escalationContactEntitlement} {
+		_, err = g.Grant(ctxTest, agent, ent.NewAssignmentEntitlement(group, entitlement))
+		require.NoError(t, err)
+	}
+	updated, _ := srv.Group(23)
+	require.Equal(t, []int64{1}, updated.Members)
+	require.Equal(t, []int64{2, 3}, updated.Observers)
+	require.Equal(t, []int64{1, 3}, updated.Leaders)
+	require.Equal(t, int64(3), *updated.EscalateTo)
+
+	for _, entitlement := range []string{observerEntitlement, leaderEntitlement, escalationContactEntitlement} {
+		_, err = g.Revoke(ctxTest, grant.NewGrant(group, entitlement, agent))
+		require.NoError(t, err)
+	}
+	updated, _ = srv.Group(23)
+	require.Equal(t, []int64{1}, updated.Members)
+	require.Equal(t, []int64{2}, updated.Observers)
+	require.Equal(t, []int64{1}, updated.Leaders)
+	require.Nil(t, updated.EscalateTo)
+}
+
+func TestConcurrentGroupGrantsAreCoalesced(t *testing.T) {
+	srv, c := newTestTenant(t)
+	g := newGroupBuilder(c)
+	group, err := agentGroupResource(ctxTest, &client.AgentGroup{ID: 21, Name: "Network"}, nil)
+	require.NoError(t, err)
+
+	// Hold the group as if it were being updated, so that the grants queue up behind it.
+	g.updates.mu.Lock()
+	g.updates.queues["21"] = []*groupChange{}
+	g.updates.mu.Unlock()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 11)
+	for id := int64(40); id < 50; id++ {
+		srv.AddAgent(client.Agent{ID: id, Active: true, FirstName: "Temp", Email: fmt.Sprintf("temp%d@example.com", id)})
+		agent, err := agentResource(ctxTest, &client.Agent{ID: id}, nil)
+		require.NoError(t, err)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := g.Grant(ctxTest, agent, ent.NewAssignmentEntitlement(group, memberEntitlement))
+			errs <- err
+		}()
+	}
+	require.Eventually(t, func() bool {
+		g.updates.mu.Lock()
+		defer g.updates.mu.Unlock()
+		return len(g.updates.queues["21"]) == 10
+	}, time.Second, time.Millisecond)
+
+	// A revoke queued with the grants is written in the same batch.
+	agent, err := agentResource(ctxTest, &client.Agent{ID: 1}, nil)
+	require.NoError(t, err)
+	go func() {
+		_, err := g.Revoke(ctxTest, grant.NewGrant(group, memberEntitlement, agent))
+		errs <- err
+	}()
+	require.Eventually(t, func() bool {
+		g.updates.mu.Lock()
+		defer g.updates.mu.Unlock()
+		return len(g.updates.queues["21"]) == 11
+	}, time.Second, time.Millisecond)
+
+	g.updates.run("21")
+	wg.Wait()
+	for range 11 {
+		require.NoError(t, <-errs)
+	}
+	require.Equal(t, []int64{40, 41, 42, 43, 44, 45, 46, 47, 48, 49}, slices.Sorted(slices.Values(srv.GroupMembers(21))))
+
+	var puts int
+	for _, request := range srv.Requests() {
+		if request == "PUT /groups/21" {
+			puts++
+		}
+	}
+	require.Equal(t, 1, puts)
+}
+
+func TestCancelledGroupGrantDoesNotFailLaterBatches(t *testing.T) {
+	srv, c := newTestTenant(t)
+	g := newGroupBuilder(c)
+	group, err := agentGroupResource(ctxTest, &client.AgentGroup{ID: 21, Name: "Network"}, nil)
+	require.NoError(t, err)
+	first, err := agentResource(ctxTest, &client.Agent{ID: 2}, nil)
+	require.NoError(t, err)
+	second, err := agentResource(ctxTest, &client.Agent{ID: 3}, nil)
+	require.NoError(t, err)
+
+	// The first grant is written while the second one is queued behind it.
+	release := srv.Hold("PUT /groups/21")
+	t.Cleanup(release)
+	ctx, cancel := context.WithCancel(ctxTest)
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := g.Grant(ctx, first, ent.NewAssignmentEntitlement(group, memberEntitlement))
+		firstErr <- err
+	}()
+	require.Eventually(t, func() bool { return countRequests(srv, "PUT /groups/21") == 1 }, time.Second, time.Millisecond)
+
+	secondErr := make(chan error, 1)
+	go func() {
+		_, err := g.Grant(ctxTest, second, ent.NewAssignmentEntitlement(group, memberEntitlement))
+		secondErr <- err
+	}()
+	require.Eventually(t, func() bool {
+		g.updates.mu.Lock()
+		defer g.updates.mu.Unlock()
+		return len(g.updates.queues["21"]) == 1
+	}, time.Second, time.Millisecond)
+
+	// The cancelled caller returns right away, without waiting for the queued batch.
+	cancel()
+	select {
+	case err := <-firstErr:
+		require.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("cancelled grant is still waiting")
+	}
+
+	release()
+	select {
+	case err := <-secondErr:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("queued grant was not written")
+	}
+	require.Contains(t, srv.GroupMembers(21), int64(3))
+}
+
+func TestApprovalRequiredGroupMembershipIsPending(t *testing.T) {
+	srv, c := newTestTenant(t)
+	srv.AddGroup(client.AgentGroup{ID: 24, Name: "Change Board", WorkspaceID: 2, Members: []int64{1}, ApprovalRequired: true})
+	g := newGroupBuilder(c)
+	group, err := agentGroupResource(ctxTest, &client.AgentGroup{ID: 24, Name: "Change Board", ApprovalRequired: true}, nil)
+	require.NoError(t, err)
+	agent, err := agentResource(ctxTest, &client.Agent{ID: 2}, nil)
+	require.NoError(t, err)
+
+	entitlements, _, _, err := g.Entitlements(ctxTest, group, &pagination.Token{})
+	require.NoError(t, err)
+	require.Equal(t, "agent_group:24:pending_member", entitlements[len(entitlements)-1].Id)
+
+	annos, err := g.Grant(ctxTest, agent, ent.NewAssignmentEntitlement(group, memberEntitlement))
+	require.NoError(t, err)
+	metadata := &v2.GrantMetadata{}
+	ok, err := annos.Pick(metadata)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.True(t, metadata.Metadata.AsMap()["pending_approval"].(bool))
+	require.Equal(t, []int64{1}, srv.GroupMembers(24))
+
+	// The pending membership is synced from the agent, not as a member of the group.
+	grants, _, _, err := g.Grants(ctxTest, group, &pagination.Token{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"1"}, grantPrincipalIDs(grants))
+	grants, _, _, err = newAgentUserBuilder(c).Grants(ctxTest, agent, &pagination.Token{})
+	require.NoError(t, err)
+	require.Len(t, grants, 1)
+	require.Equal(t, "agent_group:24:pending_member:agent:2", grants[0].Id)
+
+	_, err = g.Grant(ctxTest, agent, ent.NewAssignmentEntitlement(group, pendingMemberEntitlement))
+	require.Error(t, err)
+
+	// A pending approval can't be withdrawn, only rejected by a group leader.
+	puts := countRequests(srv, "PUT /groups/24")
+	_, err = g.Revoke(ctxTest, grant.NewGrant(group, pendingMemberEntitlement, agent))
+	require.ErrorContains(t, err, "pending approval")
+	_, err = g.Revoke(ctxTest, grant.NewGrant(group, memberEntitlement, agent))
+	require.ErrorContains(t, err, "pending approval")
+	pending, _ := srv.Agent(2)
+	require.Equal(t, []int64{24}, pending.MemberOfPendingApproval)
+	require.Equal(t, []int64{1}, srv.GroupMembers(24))
+	require.Equal(t, puts, countRequests(srv, "PUT /groups/24"))
+
+	srv.RejectMembership(24, 2)
+	annos, err = g.Revoke(ctxTest, grant.NewGrant(group, pendingMemberEntitlement, agent))
+	require.NoError(t, err)
+	require.True(t, annos.Contains(&v2.GrantAlreadyRevoked{}))
+
+	// Once approved, the agent is a member.
+	_, err = g.Grant(ctxTest, agent, ent.NewAssignmentEntitlement(group, memberEntitlement))
+	require.NoError(t, err)
+	srv.ApproveMembership(24, 2)
+	require.Equal(t, []int64{1, 2}, srv.GroupMembers(24))
+
+	// Groups without approval grant membership right away.
+	network, err := agentGroupResource(ctxTest, &client.AgentGroup{ID: 21, Name: "Network"}, nil)
+	require.NoError(t, err)
+	annos, err = g.Grant(ctxTest, agent, ent.NewAssignmentEntitlement(network, memberEntitlement))
+	require.NoError(t, err)
+	require.False(t, annos.Contains(&v2.GrantMetadata{}))
+	require.Equal(t, []int64{2}, srv.GroupMembers(21))
+}
+
+func TestRoleGrantAndRevoke(t *testing.T) {
+	srv, c := newTestTenant(t)
+	r := newRoleBuilder(c)
+	role, err := roleResource(ctxTest, &client.Roles{ID: 11, Name: "IT Ops Agent"}, nil)
+	require.NoError(t, err)
+	agent, err := agentResource(ctxTest, &client.Agent{ID: 1}, nil)
+	require.NoError(t, err)
+
+	_, err = r.Grant(ctxTest, agent, ent.NewAssignmentEntitlement(role, assignedEntitlement))
+	require.NoError(t, err)
+	updated, ok := srv.Agent(1)
+	require.True(t, ok)
+	require.Equal(t, []client.AgentRole{
+		{RoleID: 10, AssignmentScope: "entire_helpdesk"},
+		{RoleID: 11, AssignmentScope: "member_groups"},
+	}, updated.Roles)
+
+	_, err = r.Revoke(ctxTest, grant.NewGrant(role, assignedEntitlement, agent))
+	require.NoError(t, err)
+	updated, ok = srv.Agent(1)
+	require.True(t, ok)
+	require.Equal(t, []client.AgentRole{{RoleID: 10, AssignmentScope: "entire_helpdesk"}}, updated.Roles)
+}
+
+func TestRoleScopedGrantAndRevoke(t *testing.T) {
+	srv, c := newTestTenant(t)
+	r := newRoleBuilder(c)
+	role, err := roleResource(ctxTest, &client.Roles{ID: 11, Name: "IT Ops Agent"}, nil)
+	require.NoError(t, err)
+	agent, err := agentResource(ctxTest, &client.Agent{ID: 2}, nil)
+	require.NoError(t, err)
+
+	entitlements, _, _, err := r.Entitlements(ctxTest, role, &pagination.Token{})
+	require.NoError(t, err)
+	var ids []string
+	for _, e := range entitlements {
+		ids = append(ids, e.Id)
+	}
+	require.Equal(t, []string{
+		"role:11:assigned",
+		"role:11:entire_helpdesk",
+		"role:11:member_groups",
+		"role:11:specified_group_20",
+		"role:11:specified_group_21",
+		"role:11:specified_group_22",
+	}, ids)
+
+	_, err = r.Grant(ctxTest, agent, ent.NewAssignmentEntitlement(role, specifiedGroupEntitlement(20)))
+	require.NoError(t, err)
+	_, err = r.Grant(ctxTest, agent, ent.NewAssignmentEntitlement(role, specifiedGroupEntitlement(21)))
+	require.NoError(t, err)
+	updated, _ := srv.Agent(2)
+	require.Equal(t, []client.AgentRole{{RoleID: 11, AssignmentScope: "specified_groups", Groups: []int64{20, 21}}}, updated.Roles)
+
+	// Granting the assigned entitlement keeps the scope the role already has.
+	_, err = r.Grant(ctxTest, agent, ent.NewAssignmentEntitlement(role, assignedEntitlement))
+	require.NoError(t, err)
+	updated, _ = srv.Agent(2)
+	require.Equal(t, []client.AgentRole{{RoleID: 11, AssignmentScope: "specified_groups", Groups: []int64{20, 21}}}, updated.Roles)
+
+	_, err = r.Revoke(ctxTest, grant.NewGrant(role, specifiedGroupEntitlement(20), agent))
+	require.NoError(t, err)
+	updated, _ = srv.Agent(2)
+	require.Equal(t, []client.AgentRole{{RoleID: 11, AssignmentScope: "specified_groups", Groups: []int64{21}}}, updated.Roles)
+
+	// The role isn't scoped to the entire helpdesk, so revoking that scope leaves it alone.
+	_, err = r.Revoke(ctxTest, grant.NewGrant(role, assignmentScopeEntireHelpdesk, agent))
+	require.NoError(t, err)
+	updated, _ = srv.Agent(2)
+	require.Len(t, updated.Roles, 1)
+
+	_, err = r.Grant(ctxTest, agent, ent.NewAssignmentEntitlement(role, assignmentScopeEntireHelpdesk))
+	require.NoError(t, err)
+	updated, _ = srv.Agent(2)
+	require.Equal(t, []client.AgentRole{{RoleID: 11, AssignmentScope: "entire_helpdesk"}}, updated.Roles)
+
+	_, err = r.Revoke(ctxTest, grant.NewGrant(role, assignmentScopeEntireHelpdesk, agent))
+	require.NoError(t, err)
+	updated, _ = srv.Agent(2)
+	require.Empty(t, updated.Roles)
+}
+
+func TestRoleGroupEntitlementsAreLookedUpOncePerSync(t *testing.T) {
+	srv, c := newTestTenant(t)
+	c.WithWorkspaceIDs([]string{"3"})
+	r := newRoleBuilder(c)
+
+	groupLookups := func() int {
+		var rv int
+		for _, request := range srv.Requests() {
+			if strings.HasPrefix(request, "GET /groups") {
+				rv++
+			}
+		}
+		return rv
+	}
+
+	for sync := 1; sync <= 2; sync++ {
+		roles := listAll(t, r, nil, 10)
+		for _, role := range roles {
+			entitlements, _, _, err := r.Entitlements(ctxTest, role, &pagination.Token{})
+			require.NoError(t, err)
+			var ids []string
+			for _, e := range entitlements {
+				ids = append(ids, e.Id)
+			}
+			require.Contains(t, ids, fmt.Sprintf("role:%s:specified_group_22", role.Id.Resource))
+			require.NotContains(t, ids, fmt.Sprintf("role:%s:specified_group_20", role.Id.Resource))
+		}
+		require.Equal(t, sync, groupLookups())
+	}
+}
+
+func TestGrantAndRevokeAreIdempotent(t *testing.T) {
+	srv, c := newTestTenant(t)
+	exists := func(annos annotations.Annotations, err error) {
+		t.Helper()
+		require.NoError(t, err)
+		require.True(t, annos.Contains(&v2.GrantAlreadyExists{}))
+	}
+	revoked := func(annos annotations.Annotations, err error) {
+		t.Helper()
+		require.NoError(t, err)
+		require.True(t, annos.Contains(&v2.GrantAlreadyRevoked{}))
+	}
+	writes := func() int {
+		var rv int
+		for _, request := range srv.Requests() {
+			if !strings.HasPrefix(request, "GET ") {
+				rv++
+			}
+		}
+		return rv
+	}
+
+	agent, err := agentResource(ctxTest, &client.Agent{ID: 1}, nil)
+	require.NoError(t, err)
+	deletedAgent, err := agentResource(ctxTest, &client.Agent{ID: 99}, nil)
+	require.NoError(t, err)
+	requester, err := requesterUserResource(ctxTest, &client.Requesters{ID: 101}, nil)
+	require.NoError(t, err)
+	nonMember, err := requesterUserResource(ctxTest, &client.Requesters{ID: 102}, nil)
+	require.NoError(t, err)
+
+	g := newGroupBuilder(c)
+	serviceDesk, err := agentGroupResource(ctxTest, &client.AgentGroup{ID: 20, Name: "Service Desk"}, nil)
+	require.NoError(t, err)
+	deletedGroup, err := agentGroupResource(ctxTest, &client.AgentGroup{ID: 99, Name: "Deleted"}, nil)
+	require.NoError(t, err)
+	exists(g.Grant(ctxTest, agent, ent.NewAssignmentEntitlement(serviceDesk, memberEntitlement)))
+	revoked(g.Revoke(ctxTest, grant.NewGrant(serviceDesk, observerEntitlement, agent)))
+	revoked(g.Revoke(ctxTest, grant.NewGrant(deletedGroup, memberEntitlement, agent)))
+	require.Equal(t, []int64{1}, srv.GroupMembers(20))
+
+	r := newRoleBuilder(c)
+	accountAdmin, err := roleResource(ctxTest, &client.Roles{ID: 10, Name: "Account Admin"}, nil)
+	require.NoError(t, err)
+	itOps, err := roleResource(ctxTest, &client.Roles{ID: 11, Name: "IT Ops Agent"}, nil)
+	require.NoError(t, err)
+	exists(r.Grant(ctxTest, agent, ent.NewAssignmentEntitlement(accountAdmin, assignedEntitlement)))
+	exists(r.Grant(ctxTest, agent, ent.NewAssignmentEntitlement(accountAdmin, assignmentScopeEntireHelpdesk)))
+	revoked(r.Revoke(ctxTest, grant.NewGrant(itOps, assignedEntitlement, agent)))
+	revoked(r.Revoke(ctxTest, grant.NewGrant(accountAdmin, assignmentScopeMemberGroups, agent)))
+	revoked(r.Revoke(ctxTest, grant.NewGrant(accountAdmin, assignedEntitlement, deletedAgent)))
+
+	rg := newRequesterGroupBuilder(c)
+	hrTeam, err := requesterGroupResource(ctxTest, &client.RequesterGroup{ID: 30, Name: "HR Team"}, nil)
+	require.NoError(t, err)
+	deletedRequesterGroup, err := requesterGroupResource(ctxTest, &client.RequesterGroup{ID: 99, Name: "Deleted"}, nil)
+	require.NoError(t, err)
+	exists(rg.Grant(ctxTest, requester, ent.NewAssignmentEntitlement(hrTeam, memberEntitlement)))
+	revoked(rg.Revoke(ctxTest, grant.NewGrant(hrTeam, memberEntitlement, nonMember)))
+	revoked(rg.Revoke(ctxTest, grant.NewGrant(deletedRequesterGroup, memberEntitlement, requester)))
+
+	require.Zero(t, writes())
+}
+
+func TestRequesterGroupGrantAndRevoke(t *testing.T) {
+	srv, c := newTestTenant(t)
+	rg := newRequesterGroupBuilder(c)
+	group, err := requesterGroupResource(ctxTest, &client.RequesterGroup{ID: 30, Name: "HR Team"}, nil)
+	require.NoError(t, err)
+	requester, err := requesterUserResource(ctxTest, &client.Requesters{ID: 102}, nil)
+	require.NoError(t, err)
+
+	_, err = rg.Grant(ctxTest, requester, ent.NewAssignmentEntitlement(group, memberEntitlement))
+	require.NoError(t, err)
+	require.Equal(t, []int64{101, 102}, srv.RequesterGroupMembers(30))
+
+	grants, _, _, err := rg.Grants(ctxTest, group, &pagination.Token{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"101", "102"}, grantPrincipalIDs(grants))
+
+	_, err = rg.Revoke(ctxTest, grant.NewGrant(group, memberEntitlement, requester))
+	require.NoError(t, err)
+	require.Equal(t, []int64{101}, srv.RequesterGroupMembers(30))
+}
+
+func TestGrantRejectsWrongPrincipalType(t *testing.T) {
+	_, c := newTestTenant(t)
+	group, err := agentGroupResource(ctxTest, &client.AgentGroup{ID: 20}, nil)
+	require.NoError(t, err)
+	requester, err := requesterUserResource(ctxTest, &client.Requesters{ID: 101}, nil)
+	require.NoError(t, err)
+
+	_, err = newGroupBuilder(c).Grant(ctxTest, requester, ent.NewAssignmentEntitlement(group, memberEntitlement))
+	require.Error(t, err)
+}
+
+func TestRequesterGroupGrantsFollowPagination(t *testing.T) {
+	srv, c := newTestTenant(t)
+	srv.AddRequesterGroup(client.RequesterGroup{ID: 31, Name: "Finance", Type: client.RequesterGroupTypeManual}, 101, 102, 103)
+	group, err := requesterGroupResource(ctxTest, &client.RequesterGroup{ID: 31, Name: "Finance"}, nil)
+	require.NoError(t, err)
+	rg := newRequesterGroupBuilder(c)
+
+	var grants []*v2.Grant
+	token := &pagination.Token{Size: 2}
+	for {
+		page, next, _, err := rg.Grants(ctxTest, group, token)
+		require.NoError(t, err)
+		grants = append(grants, page...)
+		if next == "" {
+			break
+		}
+		token = &pagination.Token{Size: 2, Token: next}
+	}
+	require.Equal(t, []string{"101", "102", "103"}, grantPrincipalIDs(grants))
+}
+
+func TestRuleBasedRequesterGroupIsNotProvisionable(t *testing.T) {
+	srv, c := newTestTenant(t)
+	rules := map[string]interface{}{"match": "all", "conditions": []interface{}{map[string]interface{}{"field": "department", "value": "Finance"}}}
+	srv.AddRequesterGroup(client.RequesterGroup{ID: 31, Name: "Finance", Type: client.RequesterGroupTypeRuleBased, Rules: rules}, 101)
+	rg := newRequesterGroupBuilder(c)
+
+	groups := listAll(t, rg, nil, 10)
+	require.Equal(t, []string{"30", "31"}, resourceIDs(groups))
+	trait, err := rs.GetGroupTrait(groups[1])
+	require.NoError(t, err)
+	require.Equal(t, rules, trait.GetProfile().AsMap()["requester_group_rules"])
+
+	entitlements, _, _, err := rg.Entitlements(ctxTest, groups[1], &pagination.Token{})
+	require.NoError(t, err)
+	annos := annotations.Annotations(entitlements[0].Annotations)
+	require.True(t, annos.Contains(&v2.EntitlementImmutable{}))
+	entitlements, _, _, err = rg.Entitlements(ctxTest, groups[0], &pagination.Token{})
+	require.NoError(t, err)
+	annos = annotations.Annotations(entitlements[0].Annotations)
+	require.False(t, annos.Contains(&v2.EntitlementImmutable{}))
+
+	requester, err := requesterUserResource(ctxTest, &client.Requesters{ID: 102}, nil)
+	require.NoError(t, err)
+	_, err = rg.Grant(ctxTest, requester, ent.NewAssignmentEntitlement(groups[1], memberEntitlement))
+	require.ErrorContains(t, err, "rule-based")
+	_, err = rg.Revoke(ctxTest, grant.NewGrant(groups[1], memberEntitlement, &v2.ResourceId{ResourceType: requesterResourceType.Id, Resource: "101"}))
+	require.ErrorContains(t, err, "rule-based")
+	require.Equal(t, []int64{101}, srv.RequesterGroupMembers(31))
+}
+
+func TestRequesterCreateAccount(t *testing.T) {
+	srv, c := newTestTenant(t)
+	srv.AddDepartment(client.Department{ID: 40, Name: "Finance"})
+	srv.AddLocation(client.Location{ID: 50, Name: "Berlin"})
+	u := newRequesterUserBuilder(c)
+
+	profile, err := structpb.NewStruct(map[string]interface{}{
+		"first_name":           "Uma",
+		"last_name":            "User",
+		"job_title":            "Accountant",
+		"department_ids":       []interface{}{"40"},
+		"location_id":          "50",
+		"reporting_manager_id": "101",
+	})
+	require.NoError(t, err)
+	res, creds, _, err := u.CreateAccount(ctxTest, &v2.AccountInfo{
+		Emails:  []*v2.AccountInfo_Email{{Address: "uma@example.com", IsPrimary: true}},
+		Profile: profile,
+	}, &v2.LocalCredentialOptions{})
+	require.NoError(t, err)
+	require.Empty(t, creds)
+	result, ok := res.(*v2.CreateAccountResponse_SuccessResult)
+	require.True(t, ok)
+	require.True(t, result.IsCreateAccountResult)
+	require.Equal(t, requesterResourceType.Id, result.Resource.Id.ResourceType)
+
+	trait, err := rs.GetUserTrait(result.Resource)
+	require.NoError(t, err)
+	fields := trait.GetProfile().AsMap()
+	require.Equal(t, "uma@example.com", fields["email"])
+	require.Equal(t, "Accountant", fields["job_title"])
+	require.EqualValues(t, 50, fields["location_id"])
+	require.EqualValues(t, 101, fields["reporting_manager_id"])
+	require.Contains(t, srv.Requests(), "POST /requesters")
+
+	_, _, _, err = u.CreateAccount(ctxTest, &v2.AccountInfo{Profile: profile}, &v2.LocalCredentialOptions{})
+	require.ErrorContains(t, err, "email is required")
+
+	_, _, _, err = u.CreateAccount(ctxTest, &v2.AccountInfo{
+		Emails:  []*v2.AccountInfo_Email{{Address: "rae@example.com", IsPrimary: true}},
+		Profile: profile,
+	}, &v2.LocalCredentialOptions{})
+	require.Error(t, err)
+
+	for field, value := range map[string]interface{}{
+		"roles":        []interface{}{"10"},
+		"group_ids":    "21",
+		"occasional":   true,
+		"account_type": "admin",
+	} {
+		invalid, err := structpb.NewStruct(map[string]interface{}{"email": "wes@example.com", "first_name": "Wes", field: value})
+		require.NoError(t, err)
+		_, _, _, err = u.CreateAccount(ctxTest, &v2.AccountInfo{Profile: invalid}, &v2.LocalCredentialOptions{})
+		require.ErrorContains(t, err, field, field)
+	}
+	require.Equal(t, 2, countRequests(srv, "POST /requesters"))
+}
+
+func TestAgentCreateAccount(t *testing.T) {
+	srv, c := newTestTenant(t)
+	srv.AddGroup(client.AgentGroup{ID: 23, Name: "Security", WorkspaceID: 2, ApprovalRequired: true})
+	u := newRequesterUserBuilder(c)
+
+	profile, err := structpb.NewStruct(map[string]interface{}{
+		"account_type": "agent",
+		"email":        "vi@example.com",
+		"first_name":   "Vi",
+		"job_title":    "Engineer",
+		"occasional":   true,
+		"roles":        []interface{}{"11:specified_group_21", "11:specified_group_22", "10"},
+		"group_ids":    []interface{}{"21", "23"},
+	})
+	require.NoError(t, err)
+	// Requests without a resource type reach the requester account manager, which creates agents too.
+	res, err := newTestServer(t, c).CreateAccount(ctxTest, &v2.CreateAccountRequest{
+		AccountInfo: &v2.AccountInfo{Profile: profile},
+		CredentialOptions: &v2.CredentialOptions{
+			Options: &v2.CredentialOptions_NoPassword_{NoPassword: &v2.CredentialOptions_NoPassword{}},
+		},
+	})
+	require.NoError(t, err)
+	require.Empty(t, res.GetEncryptedData())
+	created := res.GetSuccess().GetResource()
+	require.Equal(t, agentUserResourceType.Id, created.GetId().GetResourceType())
+	trait, err := rs.GetUserTrait(created)
+	require.NoError(t, err)
+	require.Equal(t, agentTypeOccasional, trait.GetProfile().AsMap()["agent_type"])
+
+	id, err := strconv.ParseInt(created.Id.Resource, 10, 64)
+	require.NoError(t, err)
+	agent, ok := srv.Agent(id)
+	require.True(t, ok)
+	require.True(t, agent.Occasional)
+	require.Equal(t, "Engineer", agent.JobTitle)
+	require.Equal(t, []client.AgentRole{
+		{RoleID: 11, AssignmentScope: assignmentScopeSpecifiedGroups, Groups: []int64{21, 22}},
+		{RoleID: 10, AssignmentScope: assignmentScopeMemberGroups},
+	}, agent.Roles)
+	require.Contains(t, srv.GroupMembers(21), id)
+	require.NotContains(t, srv.GroupMembers(23), id)
+	require.Equal(t, []int64{23}, agent.MemberOfPendingApproval)
+
+	for name, roles := range map[string]interface{}{
+		"roles are required":            []interface{}{},
+		"conflicting assignment scopes": []interface{}{"10:entire_helpdesk", "10:member_groups"},
+		"unknown role entitlement":      []interface{}{"10:everything"},
+		"invalid roles entry \"admin\"": []interface{}{"admin"},
+	} {
+		profile.Fields["roles"], err = structpb.NewValue(roles)
+		require.NoError(t, err)
+		_, _, _, err = u.CreateAccount(ctxTest, &v2.AccountInfo{Profile: profile}, &v2.LocalCredentialOptions{})
+		require.ErrorContains(t, err, name)
+	}
+}
+
+func TestUserDeletion(t *testing.T) {
+	srv, c := newTestTenant(t)
+	deletes := func() []string {
+		return slices.DeleteFunc(srv.Requests(), func(request string) bool { return !strings.HasPrefix(request, "DELETE ") })
+	}
+
+	agents := newAgentUserBuilder(c)
+	for range 2 {
+		_, err := agents.Delete(ctxTest, &v2.ResourceId{ResourceType: agentUserResourceType.Id, Resource: "2"})
+		require.NoError(t, err)
+	}
+	_, err := agents.Delete(ctxTest, &v2.ResourceId{ResourceType: agentUserResourceType.Id, Resource: "99"})
+	require.NoError(t, err)
+	agent, _ := srv.Agent(2)
+	require.False(t, agent.Active)
+
+	for range 2 {
+		_, err = newRequesterUserBuilder(c).Delete(ctxTest, &v2.ResourceId{ResourceType: requesterResourceType.Id, Resource: "102"})
+		require.NoError(t, err)
+	}
+	requester, ok := srv.Requester(102)
+	require.True(t, ok)
+	require.False(t, requester.Active)
+	require.Equal(t, []string{"DELETE /agents/2", "DELETE /requesters/102"}, deletes())
+
+	conn, err := New(ctxTest, "", "", c, WithForgetDeletedRequesters(true))
+	require.NoError(t, err)
+	metadata, err := conn.Metadata(ctxTest)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"agent_deletion_mode":     deletionModeDeactivate,
+		"requester_deletion_mode": deletionModeForget,
+	}, metadata.Profile.AsMap())
+
+	var requesters connectorbuilder.ResourceDeleter
+	for _, syncer := range conn.ResourceSyncers(ctxTest) {
+		if syncer.ResourceType(ctxTest).Id == requesterResourceType.Id {
+			requesters = syncer.(connectorbuilder.ResourceDeleter)
+		}
+	}
+	for range 2 {
+		_, err = requesters.Delete(ctxTest, &v2.ResourceId{ResourceType: requesterResourceType.Id, Resource: "101"})
+		require.NoError(t, err)
+	}
+	_, ok = srv.Requester(101)
+	require.False(t, ok)
+	require.Empty(t, srv.RequesterGroupMembers(30))
+	require.Equal(t, []string{"DELETE /agents/2", "DELETE /requesters/102", "DELETE /requesters/101/forget"}, deletes())
+}
+
+func TestConvertUserActions(t *testing.T) {
+	srv, c := newTestTenant(t)
+	server := newTestServer(t, c)
+
+	schemas, err := server.ListActionSchemas(ctxTest, &v2.ListActionSchemasRequest{ResourceTypeId: requesterResourceType.Id})
+	require.NoError(t, err)
+	var names []string
+	for _, schema := range schemas.GetSchemas() {
+		names = append(names, schema.GetName())
+	}
+	require.ElementsMatch(t, []string{convertToAgentAction, disableAction, enableAction}, names)
+
+	for range 2 {
+		resource := invokeUserAction(t, server, convertToAgentAction, requesterResourceType, "102")
+		require.Equal(t, &v2.ResourceId{ResourceType: agentUserResourceType.Id, Resource: "102"}, resource.Id)
+	}
+	agent, ok := srv.Agent(102)
+	require.True(t, ok)
+	require.False(t, agent.Occasional)
+	_, ok = srv.Requester(102)
+	require.False(t, ok)
+
+	for range 2 {
+		resource := invokeUserAction(t, server, convertToRequesterAction, agentUserResourceType, "1")
+		require.Equal(t, &v2.ResourceId{ResourceType: requesterResourceType.Id, Resource: "1"}, resource.Id)
+	}
+	_, ok = srv.Agent(1)
+	require.False(t, ok)
+	require.NotContains(t, srv.GroupMembers(20), int64(1))
+	require.Equal(t, 1, countRequests(srv, "PUT /requesters/102/convert_to_agent"))
+	require.Equal(t, 1, countRequests(srv, "PUT /agents/1/convert_to_requester"))
+}
+
+func TestUserStatusActions(t *testing.T) {
+	srv, c := newTestTenant(t)
+	server := newTestServer(t, c)
+
+	status := func(resource *v2.Resource) v2.UserTrait_Status_Status {
+		trait, err := rs.GetUserTrait(resource)
+		require.NoError(t, err)
+		return trait.GetStatus().GetStatus()
+	}
+
+	for _, user := range []struct {
+		resourceType *v2.ResourceType
+		id           string
+		deactivate   string
+		reactivate   string
+	}{
+		{agentUserResourceType, "2", "DELETE /agents/2", "PUT /agents/2/reactivate"},
+		{requesterResourceType, "102", "DELETE /requesters/102", "PUT /requesters/102/reactivate"},
+	} {
+		for range 2 {
+			resource := invokeUserAction(t, server, disableAction, user.resourceType, user.id)
+			require.Equal(t, v2.UserTrait_Status_STATUS_DISABLED, status(resource))
+		}
+		for range 2 {
+			resource := invokeUserAction(t, server, enableAction, user.resourceType, user.id)
+			require.Equal(t, v2.UserTrait_Status_STATUS_ENABLED, status(resource))
+		}
+		require.Equal(t, 1, countRequests(srv, user.deactivate))
+		require.Equal(t, 1, countRequests(srv, user.reactivate))
+	}
+	agent, _ := srv.Agent(2)
+	require.True(t, agent.Active)
+	requester, _ := srv.Requester(102)
+	require.True(t, requester.Active)
+}
+
+// newTestServer returns the connector server of a connector using c, which dispatches actions to its builders.
+func newTestServer(t *testing.T, c *client.FreshServiceClient) types.ConnectorServer {
+	t.Helper()
+	conn, err := New(ctxTest, "", "", c)
+	require.NoError(t, err)
+	server, err := connectorbuilder.NewConnector(ctxTest, conn)
+	require.NoError(t, err)
+	return server
+}
+
+// invokeUserAction invokes an action on a user, and returns the user resource it returned.
+func invokeUserAction(t *testing.T, server types.ConnectorServer, name string, resourceType *v2.ResourceType, id string) *v2.Resource {
+	t.Helper()
+	args := &structpb.Struct{Fields: map[string]*structpb.Value{
+		resourceIDArg: structpb.NewStructValue(&structpb.Struct{Fields: map[string]*structpb.Value{
+			"resource_type_id": structpb.NewStringValue(resourceType.Id),
+			"resource_id":      structpb.NewStringValue(id),
+		}}),
+	}}
+	res, err := server.InvokeAction(ctxTest, &v2.InvokeActionRequest{Name: name, ResourceTypeId: resourceType.Id, Args: args})
+	require.NoError(t, err)
+	require.Equal(t, v2.BatonActionStatus_BATON_ACTION_STATUS_COMPLETE, res.GetStatus(), res.GetResponse().AsMap())
+	resource, ok := actions.GetResourceFieldArg(res.GetResponse(), resourceReturned)
+	require.True(t, ok)
+	return resource
+}
+
+func countRequests(srv *clienttest.Server, request string) int {
+	var rv int
+	for _, r := range srv.Requests() {
+		if r == request {
+			rv++
+		}
+	}
+	return rv
+}
+
+// countRequestsWithPrefix counts the requests starting with prefix, like "GET /agents?" for listings with a query.
+func countRequestsWithPrefix(srv *clienttest.Server, prefix string) int {
+	var rv int
+	for _, r := range srv.Requests() {
+		if strings.HasPrefix(r, prefix) {
+			rv++
+		}
+	}
+	return rv
+}
@@ -0,0 +1,238 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/conductorone/baton-freshservice/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+const headEntitlement = "head"
+
+type departmentBuilder struct {
+	resourceType *v2.ResourceType
+	client       *client.FreshServiceClient
+	principals   *userPrincipals
+}
+
+func (d *departmentBuilder) ResourceType(_ context.Context) *v2.ResourceType {
+	return departmentResourceType
+}
+
+func (d *departmentBuilder) List(ctx context.Context, _ *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	var rv []*v2.Resource
+	bag, pageToken, err := getToken(pToken, departmentResourceType)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	// A sync lists departments from their first page before their grants, so agents are looked up again.
+	if pageToken == 0 {
+		d.principals.reset()
+	}
+
+	departments, nextPageToken, annotation, err := d.client.ListDepartments(ctx, client.PageOptions{
+		PerPage: pToken.Size,
+		Page:    pageToken,
+	})
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	err = bag.Next(nextPageToken)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	for _, department := range departments.Departments {
+		departmentCopy := department
+		dr, err := departmentResource(ctx, &departmentCopy, nil)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		rv = append(rv, dr)
+	}
+
+	nextPageToken, err = bag.Marshal()
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return rv, nextPageToken, annotation, nil
+}
+
+func (d *departmentBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return []*v2.Entitlement{
+		ent.NewAssignmentEntitlement(resource, memberEntitlement,
+			ent.WithGrantableTo(agentUserResourceType, requesterResourceType),
+			ent.WithDescription(fmt.Sprintf("Member of %s department in FreshService", resource.DisplayName)),
+			ent.WithDisplayName(fmt.Sprintf("%s Department %s", resource.DisplayName, memberEntitlement)),
+		),
+		ent.NewAssignmentEntitlement(resource, headEntitlement,
+			ent.WithGrantableTo(agentUserResourceType, requesterResourceType),
+			ent.WithDescription(fmt.Sprintf("Head or prime user of %s department in FreshService", resource.DisplayName)),
+			ent.WithDisplayName(fmt.Sprintf("%s Department %s", resource.DisplayName, headEntitlement)),
+		),
+	}, "", nil, nil
+}
+
+// Grants returns the head and prime user of a department. Memberships are granted by the users themselves,
+// as only users know their department IDs.
+func (d *departmentBuilder) Grants(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	trait, err := rs.GetGroupTrait(resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	var (
+		rv   []*v2.Grant
+		seen []int64
+	)
+	for _, key := range []string{"head_user_id", "prime_user_id"} {
+		userID, ok := rs.GetProfileInt64Value(trait.GetProfile(), key)
+		if !ok || userID == 0 || slices.Contains(seen, userID) {
+			continue
+		}
+		seen = append(seen, userID)
+
+		principal, err := d.principals.resourceID(ctx, userID)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		rv = append(rv, grant.NewGrant(resource, headEntitlement, principal))
+	}
+
+	return rv, "", nil, nil
+}
+
+// userDepartmentGrants returns the department member grants of a user.
+func userDepartmentGrants(ctx context.Context, userID *v2.ResourceId, departmentIDs []int64) ([]*v2.Grant, error) {
+	rv := make([]*v2.Grant, 0, len(departmentIDs))
+	for _, departmentID := range departmentIDs {
+		department, err := departmentResource(ctx, &client.Department{ID: departmentID}, nil)
+		if err != nil {
+			return nil, err
+		}
+		rv = append(rv, grant.NewGrant(department, memberEntitlement, userID))
+	}
+	return rv, nil
+}
+
+func (d *departmentBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
+	err := checkDepartmentProvisioning(ctx, principal, entitlement)
+	if err != nil {
+		return nil, err
+	}
+
+	departmentID, err := strconv.ParseInt(entitlement.Resource.Id.Resource, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	departmentIDs, err := d.userDepartments(ctx, principal)
+	if err != nil {
+		return nil, err
+	}
+	if slices.Contains(departmentIDs, departmentID) {
+		ctxzap.Extract(ctx).Info(
+			"freshservice-connector: user is already a member of the department",
+			zap.String("principal_id", principal.Id.Resource),
+			zap.Int64("department_id", departmentID),
+		)
+		return annotations.New(&v2.GrantAlreadyExists{}), nil
+	}
+	return d.setDepartments(ctx, principal, append(departmentIDs, departmentID))
+}
+
+func (d *departmentBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	err := checkDepartmentProvisioning(ctx, grant.Principal, grant.Entitlement)
+	if err != nil {
+		return nil, err
+	}
+
+	departmentID, err := strconv.ParseInt(grant.Entitlement.Resource.Id.Resource, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	departmentIDs, err := d.userDepartments(ctx, grant.Principal)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(departmentIDs, departmentID) {
+		ctxzap.Extract(ctx).Info(
+			"freshservice-connector: user is no longer a member of the department",
+			zap.String("principal_id", grant.Principal.Id.Resource),
+			zap.Int64("department_id", departmentID),
+		)
+		return annotations.New(&v2.GrantAlreadyRevoked{}), nil
+	}
+	return d.setDepartments(ctx, grant.Principal, slices.DeleteFunc(departmentIDs, func(id int64) bool { return id == departmentID }))
+}
+
+// checkDepartmentProvisioning rejects changes Grant and Revoke can't make: only department membership of users
+// is provisioned, the head is managed on the department itself.
+func checkDepartmentProvisioning(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) error {
+	l := ctxzap.Extract(ctx)
+	if principal.Id.ResourceType != agentUserResourceType.Id && principal.Id.ResourceType != requesterResourceType.Id {
+		l.Warn(
+			"freshservice-connector: only users can be granted department membership",
+			zap.String("principal_type", principal.Id.ResourceType),
+			zap.String("principal_id", principal.Id.Resource),
+		)
+		return fmt.Errorf("freshservice-connector: only users can be granted department membership")
+	}
+
+	if slug := entitlementSlug(entitlement); slug != memberEntitlement {
+		l.Warn(
+			"freshservice-connector: only department membership can be provisioned",
+			zap.String("entitlement_id", entitlement.Id),
+		)
+		return fmt.Errorf("freshservice-connector: only department membership can be provisioned, not %q", slug)
+	}
+
+	return nil
+}
+
+// userDepartments returns the current departments of an agent or requester.
+func (d *departmentBuilder) userDepartments(ctx context.Context, principal *v2.Resource) ([]int64, error) {
+	userId := principal.Id.Resource
+	if principal.Id.ResourceType == agentUserResourceType.Id {
+		agent, _, err := d.client.GetAgentDetail(ctx, userId)
+		if err != nil {
+			return nil, err
+		}
+		return agent.Agent.DepartmentIDs, nil
+	}
+
+	requester, _, err := d.client.GetRequesterDetail(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	return requester.Requester.DepartmentIDs, nil
+}
+
+// setDepartments replaces the departments of an agent or requester.
+func (d *departmentBuilder) setDepartments(ctx context.Context, principal *v2.Resource, departmentIDs []int64) (annotations.Annotations, error) {
+	if principal.Id.ResourceType == agentUserResourceType.Id {
+		return d.client.UpdateAgentDepartments(ctx, departmentIDs, principal.Id.Resource)
+	}
+	return d.client.UpdateRequesterDepartments(ctx, departmentIDs, principal.Id.Resource)
+}
+
+func newDepartmentBuilder(c *client.FreshServiceClient) *departmentBuilder {
+	return &departmentBuilder{
+		resourceType: departmentResourceType,
+		client:       c,
+		principals:   newUserPrincipals(c),
+	}
+}
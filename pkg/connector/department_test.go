@@ -0,0 +1,150 @@
+package connector
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/conductorone/baton-freshservice/pkg/client"
+	"github.com/conductorone/baton-freshservice/pkg/client/clienttest"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	"github.com/stretchr/testify/require"
+)
+
+func newDepartmentTenant(t *testing.T) (*clienttest.Server, *client.FreshServiceClient) {
+	t.Helper()
+	srv, c := newTestTenant(t)
+	srv.AddDepartment(client.Department{ID: 40, Name: "Engineering", HeadUserID: 1, PrimeUserID: 101})
+	srv.AddDepartment(client.Department{ID: 41, Name: "Finance"})
+	srv.AddAgent(client.Agent{ID: 2, Active: true, FirstName: "Bo", LastName: "Agent", Email: "bo@example.com", DepartmentIDs: []int64{41}})
+	srv.AddRequester(client.Requesters{ID: 102, Active: true, FirstName: "Sam", PrimaryEmail: "sam@example.com", DepartmentIDs: []int64{40}})
+	return srv, c
+}
+
+func TestDepartmentHeadGrants(t *testing.T) {
+	_, c := newDepartmentTenant(t)
+	d := newDepartmentBuilder(c)
+
+	departments := listAll(t, d, nil, 1)
+	require.Equal(t, []string{"40", "41"}, resourceIDs(departments))
+
+	grants, next, _, err := d.Grants(ctxTest, departments[0], &pagination.Token{})
+	require.NoError(t, err)
+	require.Empty(t, next)
+	require.Equal(t, []string{"department:40:head:agent:1", "department:40:head:requester:101"}, []string{grants[0].Id, grants[1].Id})
+
+	grants, _, _, err = d.Grants(ctxTest, departments[1], &pagination.Token{})
+	require.NoError(t, err)
+	require.Empty(t, grants)
+}
+
+func TestDepartmentUsersAreResolvedFromOneAgentListing(t *testing.T) {
+	srv, c := newDepartmentTenant(t)
+	srv.AddAgent(client.Agent{ID: 4, Active: false, FirstName: "Di", Email: "di@example.com"})
+	srv.AddDepartment(client.Department{ID: 42, Name: "Legal", HeadUserID: 4, PrimeUserID: 102})
+	d := newDepartmentBuilder(c)
+
+	var ids []string
+	departments := listAll(t, d, nil, 10)
+	for _, department := range departments {
+		grants, _, _, err := d.Grants(ctxTest, department, &pagination.Token{})
+		require.NoError(t, err)
+		for _, g := range grants {
+			ids = append(ids, g.Principal.Id.ResourceType+":"+g.Principal.Id.Resource)
+		}
+	}
+	// Deactivated agents are still agents.
+	require.Contains(t, ids, "agent:4")
+	require.Contains(t, ids, "requester:102")
+
+	var listings, lookups int
+	for _, r := range srv.Requests() {
+		switch {
+		case strings.HasPrefix(r, "GET /agents?"):
+			listings++
+		case strings.HasPrefix(r, "GET /agents/"):
+			lookups++
+		}
+	}
+	// One pass over the active agents and one over the deactivated ones, whatever the number of users.
+	require.Equal(t, 2, listings)
+	require.Zero(t, lookups)
+
+	// The next sync lists the agents again.
+	listAll(t, d, nil, 10)
+	_, _, _, err := d.Grants(ctxTest, departments[0], &pagination.Token{})
+	require.NoError(t, err)
+	listings = 0
+	for _, r := range srv.Requests() {
+		if strings.HasPrefix(r, "GET /agents?") {
+			listings++
+		}
+	}
+	require.Equal(t, 4, listings)
+}
+
+func TestUsersGrantDepartmentMembership(t *testing.T) {
+	_, c := newDepartmentTenant(t)
+
+	var requester *v2.Resource
+	for _, r := range listAll(t, newRequesterUserBuilder(c), nil, 10) {
+		if r.Id.Resource == "102" {
+			requester = r
+		}
+	}
+	require.NotNil(t, requester)
+	grants, _, _, err := newRequesterUserBuilder(c).Grants(ctxTest, requester, &pagination.Token{})
+	require.NoError(t, err)
+	require.Len(t, grants, 1)
+	require.Equal(t, "department:40:member:requester:102", grants[0].Id)
+
+	agent, err := agentResource(ctxTest, &client.Agent{ID: 2}, nil)
+	require.NoError(t, err)
+	grants, _, _, err = newAgentUserBuilder(c).Grants(ctxTest, agent, &pagination.Token{})
+	require.NoError(t, err)
+	require.Len(t, grants, 1)
+	require.Equal(t, "department:41:member:agent:2", grants[0].Id)
+}
+
+func TestDepartmentGrantAndRevoke(t *testing.T) {
+	srv, c := newDepartmentTenant(t)
+	d := newDepartmentBuilder(c)
+	department, err := departmentResource(ctxTest, &client.Department{ID: 41, Name: "Finance"}, nil)
+	require.NoError(t, err)
+	requester, err := requesterUserResource(ctxTest, &client.Requesters{ID: 102}, nil)
+	require.NoError(t, err)
+	agent, err := agentResource(ctxTest, &client.Agent{ID: 3}, nil)
+	require.NoError(t, err)
+
+	_, err = d.Grant(ctxTest, requester, ent.NewAssignmentEntitlement(department, memberEntitlement))
+	require.NoError(t, err)
+	updated, ok := srv.Requester(102)
+	require.True(t, ok)
+	require.Equal(t, []int64{40, 41}, updated.DepartmentIDs)
+
+	_, err = d.Grant(ctxTest, agent, ent.NewAssignmentEntitlement(department, memberEntitlement))
+	require.NoError(t, err)
+	updatedAgent, ok := srv.Agent(3)
+	require.True(t, ok)
+	require.Equal(t, []int64{41}, updatedAgent.DepartmentIDs)
+
+	_, err = d.Revoke(ctxTest, grant.NewGrant(department, memberEntitlement, requester))
+	require.NoError(t, err)
+	updated, ok = srv.Requester(102)
+	require.True(t, ok)
+	require.Equal(t, []int64{40}, updated.DepartmentIDs)
+
+	annos, err := d.Grant(ctxTest, agent, ent.NewAssignmentEntitlement(department, memberEntitlement))
+	require.NoError(t, err)
+	require.True(t, annos.Contains(&v2.GrantAlreadyExists{}))
+	annos, err = d.Revoke(ctxTest, grant.NewGrant(department, memberEntitlement, requester))
+	require.NoError(t, err)
+	require.True(t, annos.Contains(&v2.GrantAlreadyRevoked{}))
+	require.Equal(t, 2, countRequests(srv, "PUT /requesters/102"))
+	require.Equal(t, 1, countRequests(srv, "PUT /agents/3"))
+
+	_, err = d.Grant(ctxTest, agent, ent.NewAssignmentEntitlement(department, headEntitlement))
+	require.Error(t, err)
+}
@@ -0,0 +1,311 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/conductorone/baton-freshservice/pkg/client"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+// errPendingApproval is returned for the removal of an agent whose membership is pending approval.
+var errPendingApproval = errors.New("freshservice-connector: group membership pending approval can't be withdrawn")
+
+// maxGroupUpdateAttempts is how many times a batch of changes is written to a group before giving up on the changes
+// that still don't show on it.
+const maxGroupUpdateAttempts = 3
+
+// groupChange adds an agent to, or removes it from, the agents holding an entitlement of a group.
+type groupChange struct {
+	// ctx is the context of the caller waiting for the change.
+	ctx         context.Context
+	entitlement string
+	agentID     int64
+	add         bool
+	// noop is set when the group already reflects the change, which then isn't written.
+	noop bool
+	// pendingApproval is set when the agent to remove is only pending approval, which can't be withdrawn, so the
+	// change isn't written.
+	pendingApproval bool
+	done            chan groupChangeResult
+}
+
+type groupChangeResult struct {
+	group      *client.AgentGroup
+	annotation annotations.Annotations
+	noop       bool
+	err        error
+}
+
+// groupUpdates serializes the changes to the agents of each group. Freshservice only updates the members, observers
+// and leaders of a group as whole lists, so concurrent read-modify-writes would drop each other's changes. Changes
+// submitted while a group is being updated are queued, and written together by the next update with a single read
+// and write. Every update is read back to verify it was applied. Updates are written in the background, so that
+// callers only wait for their own change.
+type groupUpdates struct {
+	client *client.FreshServiceClient
+	mu     sync.Mutex
+	// queues holds the changes waiting for each group being updated. A group has a queue, possibly empty, for as
+	// long as a caller is updating it.
+	queues map[string][]*groupChange
+}
+
+func newGroupUpdates(c *client.FreshServiceClient) *groupUpdates {
+	return &groupUpdates{
+		client: c,
+		queues: make(map[string][]*groupChange),
+	}
+}
+
+// apply adds the agent to, or removes it from, the agents holding entitlement in a group, and returns the group as
+// it was before the update and whether the group already reflected the change. A group has a single escalation
+// contact, so adding one replaces the previous one.
+func (u *groupUpdates) apply(
+	ctx context.Context,
+	groupId string,
+	entitlement string,
+	agentID int64,
+	add bool,
+) (*client.AgentGroup, annotations.Annotations, bool, error) {
+	if !slices.Contains(groupEntitlements, entitlement) {
+		return nil, nil, false, fmt.Errorf("freshservice-connector: unknown group entitlement %q", entitlement)
+	}
+
+	change := &groupChange{
+		ctx:         ctx,
+		entitlement: entitlement,
+		agentID:     agentID,
+		add:         add,
+		done:        make(chan groupChangeResult, 1),
+	}
+
+	u.mu.Lock()
+	queue, updating := u.queues[groupId]
+	u.queues[groupId] = append(queue, change)
+	u.mu.Unlock()
+
+	// The first caller for a group starts writing batches of queued changes until none are left, its own change
+	// included.
+	if !updating {
+		go u.run(groupId)
+	}
+
+	select {
+	case result := <-change.done:
+		return result.group, result.annotation, result.noop, result.err
+	case <-ctx.Done():
+		u.withdraw(groupId, change)
+		return nil, nil, false, ctx.Err()
+	}
+}
+
+// withdraw removes a change its caller gave up on from the queue of the group, unless it is already being written.
+func (u *groupUpdates) withdraw(groupId string, change *groupChange) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if queue, ok := u.queues[groupId]; ok {
+		u.queues[groupId] = slices.DeleteFunc(queue, func(queued *groupChange) bool { return queued == change })
+	}
+}
+
+// run writes the changes queued for a group, batch after batch, until its queue is empty.
+func (u *groupUpdates) run(groupId string) {
+	for {
+		u.mu.Lock()
+		batch := u.queues[groupId]
+		if len(batch) == 0 {
+			delete(u.queues, groupId)
+			u.mu.Unlock()
+			return
+		}
+		u.queues[groupId] = []*groupChange{}
+		u.mu.Unlock()
+
+		ctx, cancel := batchContext(batch)
+		group, annotation, err := u.write(ctx, groupId, batch)
+		cancel()
+		for _, change := range batch {
+			result := groupChangeResult{group: group, annotation: slices.Clone(annotation), noop: change.noop, err: err}
+			if err == nil && change.pendingApproval {
+				result.err = errPendingApproval
+			}
+			change.done <- result
+		}
+	}
+}
+
+// batchContext returns the context a batch is written with. It has the values of the context of the first change,
+// and is only cancelled once the callers of all the changes gave up.
+func batchContext(batch []*groupChange) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(batch[0].ctx))
+	go func() {
+		for _, change := range batch {
+			select {
+			case <-change.ctx.Done():
+			case <-ctx.Done():
+				return
+			}
+		}
+		cancel()
+	}()
+	return ctx, cancel
+}
+
+// write applies a batch of changes to a group with one write, and reads the group back to check them. Changes that
+// don't show, because the group changed concurrently outside the connector, are written again. Changes the group
+// already reflects are marked as no-ops, and nothing is written when all of them are.
+func (u *groupUpdates) write(ctx context.Context, groupId string, batch []*groupChange) (*client.AgentGroup, annotations.Annotations, error) {
+	groupDetail, annotation, err := u.client.GetAgentGroupDetail(ctx, groupId)
+	if err != nil {
+		return nil, nil, err
+	}
+	before := groupDetail.Group
+	current := groupDetail.Group
+
+	var pending []*groupChange
+	for _, change := range batch {
+		holds, err := u.holds(ctx, &before, change)
+		if err != nil {
+			return nil, nil, err
+		}
+		change.noop = holds == change.add
+		change.pendingApproval = !change.add && holds && !holdsGroupEntitlement(&before, change.entitlement, change.agentID)
+		if !change.noop && !change.pendingApproval {
+			pending = append(pending, change)
+		}
+	}
+
+	for attempt := 0; attempt < maxGroupUpdateAttempts && len(pending) > 0; attempt++ {
+		update, expected := applyGroupChanges(&current, pending)
+		_, err = u.client.UpdateAgentGroupAgents(ctx, groupId, update)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		groupDetail, _, err = u.client.GetAgentGroupDetail(ctx, groupId)
+		if err != nil {
+			return nil, nil, err
+		}
+		current = groupDetail.Group
+
+		pending, err = u.unapplied(ctx, &expected, &current, pending)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if len(pending) > 0 {
+		ctxzap.Extract(ctx).Warn(
+			"freshservice-connector: group update was not applied",
+			zap.String("group_id", groupId),
+			zap.Int("changes", len(pending)),
+		)
+		return nil, nil, fmt.Errorf("freshservice-connector: %d changes to the agents of group %s were not applied", len(pending), groupId)
+	}
+
+	return &before, annotation, nil
+}
+
+// applyGroupChanges returns the update applying changes to group, and the group as expected after it. Only the
+// agent lists the changes touch are part of the update.
+func applyGroupChanges(group *client.AgentGroup, changes []*groupChange) (*client.UpdateAgentGroupAgents, client.AgentGroup) {
+	expected := *group
+	update := &client.UpdateAgentGroupAgents{}
+	for _, change := range changes {
+		switch change.entitlement {
+		case memberEntitlement:
+			expected.Members = applyGroupChange(expected.Members, change)
+			update.Members = &expected.Members
+		case observerEntitlement:
+			expected.Observers = applyGroupChange(expected.Observers, change)
+			update.Observers = &expected.Observers
+		case leaderEntitlement:
+			expected.Leaders = applyGroupChange(expected.Leaders, change)
+			update.Leaders = &expected.Leaders
+		case escalationContactEntitlement:
+			switch {
+			case change.add:
+				expected.EscalateTo = &change.agentID
+			case expected.EscalateTo != nil && *expected.EscalateTo == change.agentID:
+				expected.EscalateTo = nil
+			}
+			update.EscalateTo = &expected.EscalateTo
+		}
+	}
+	return update, expected
+}
+
+// applyGroupChange returns a copy of agents with the change applied.
+func applyGroupChange(agents []int64, change *groupChange) []int64 {
+	rv := slices.Clone(agents)
+	if rv == nil {
+		rv = []int64{}
+	}
+	if !change.add {
+		return slices.DeleteFunc(rv, func(agent int64) bool { return agent == change.agentID })
+	}
+	if !slices.Contains(rv, change.agentID) {
+		rv = append(rv, change.agentID)
+	}
+	return rv
+}
+
+// holdsGroupEntitlement reports whether the agent holds entitlement in group.
+func holdsGroupEntitlement(group *client.AgentGroup, entitlement string, agentID int64) bool {
+	switch entitlement {
+	case memberEntitlement:
+		return slices.Contains(group.Members, agentID)
+	case observerEntitlement:
+		return slices.Contains(group.Observers, agentID)
+	case leaderEntitlement:
+		return slices.Contains(group.Leaders, agentID)
+	default:
+		return group.EscalateTo != nil && *group.EscalateTo == agentID
+	}
+}
+
+// holds reports whether the agent of the change holds its entitlement in group. Agents added to an
+// approval-required group aren't members until approved, so their pending membership counts as holding it.
+func (u *groupUpdates) holds(ctx context.Context, group *client.AgentGroup, change *groupChange) (bool, error) {
+	if holdsGroupEntitlement(group, change.entitlement, change.agentID) {
+		return true, nil
+	}
+	if change.entitlement != memberEntitlement || !group.ApprovalRequired {
+		return false, nil
+	}
+
+	agent, _, err := u.client.GetAgentDetail(ctx, strconv.FormatInt(change.agentID, 10))
+	switch {
+	case client.IsNotFound(err):
+		return false, nil
+	case err != nil:
+		return false, err
+	}
+	return slices.Contains(agent.Agent.MemberOfPendingApproval, group.ID), nil
+}
+
+// unapplied returns the changes whose outcome in expected doesn't show in actual.
+func (u *groupUpdates) unapplied(ctx context.Context, expected *client.AgentGroup, actual *client.AgentGroup, changes []*groupChange) ([]*groupChange, error) {
+	var rv []*groupChange
+	for _, change := range changes {
+		want := holdsGroupEntitlement(expected, change.entitlement, change.agentID)
+		if holdsGroupEntitlement(actual, change.entitlement, change.agentID) == want {
+			continue
+		}
+
+		holds, err := u.holds(ctx, actual, change)
+		if err != nil {
+			return nil, err
+		}
+		if holds != want {
+			rv = append(rv, change)
+		}
+	}
+	return rv, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/conductorone/baton-freshservice/pkg/client"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
@@ -14,12 +15,22 @@ import (
 func requesterUserResource(ctx context.Context, user *client.Requesters, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
 	userStatus := v2.UserTrait_Status_STATUS_ENABLED
 	profile := map[string]interface{}{
-		"user_id":    user.ID,
-		"login":      user.PrimaryEmail,
-		"first_name": user.FirstName,
-		"last_name":  user.LastName,
-		"email":      user.PrimaryEmail,
-		"is_agent":   false,
+		"user_id":        user.ID,
+		"login":          user.PrimaryEmail,
+		"first_name":     user.FirstName,
+		"last_name":      user.LastName,
+		"email":          user.PrimaryEmail,
+		"is_agent":       false,
+		"department_ids": departmentIDsProfile(user.DepartmentIDs),
+	}
+	if user.LocationID != nil {
+		profile["location_id"] = *user.LocationID
+	}
+	if user.JobTitle != "" {
+		profile["job_title"] = user.JobTitle
+	}
+	if user.ReportingManagerID != nil {
+		profile["reporting_manager_id"] = *user.ReportingManagerID
 	}
 
 	switch user.Active {
@@ -57,12 +68,23 @@ func requesterUserResource(ctx context.Context, user *client.Requesters, parentR
 func agentResource(ctx context.Context, user *client.Agent, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
 	userStatus := v2.UserTrait_Status_STATUS_ENABLED
 	profile := map[string]interface{}{
-		"user_id":    user.ID,
-		"login":      user.Email,
-		"first_name": user.FirstName,
-		"last_name":  user.LastName,
-		"email":      user.Email,
-		"is_agent":   true,
+		"user_id":        user.ID,
+		"login":          user.Email,
+		"first_name":     user.FirstName,
+		"last_name":      user.LastName,
+		"email":          user.Email,
+		"is_agent":       true,
+		"agent_type":     agentType(user),
+		"department_ids": departmentIDsProfile(user.DepartmentIDs),
+	}
+	if user.LocationID != nil {
+		profile["location_id"] = *user.LocationID
+	}
+	if user.JobTitle != "" {
+		profile["job_title"] = user.JobTitle
+	}
+	if user.ReportingManagerID != nil {
+		profile["reporting_manager_id"] = *user.ReportingManagerID
 	}
 
 	switch user.Active {
@@ -98,11 +120,21 @@ func agentResource(ctx context.Context, user *client.Agent, parentResourceID *v2
 	return ret, nil
 }
 
+// agentType is "occasional" for day-pass agents and "fulltime" for agents holding a full-time license.
+func agentType(user *client.Agent) string {
+	if user.Occasional {
+		return agentTypeOccasional
+	}
+	return agentTypeFulltime
+}
+
 // Create a new connector resource for FreshService.
 func agentGroupResource(ctx context.Context, group *client.AgentGroup, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
 	profile := map[string]interface{}{
-		"group_id":   group.ID,
-		"group_name": group.Name,
+		"group_id":          group.ID,
+		"group_name":        group.Name,
+		"workspace_id":      group.WorkspaceID,
+		"approval_required": group.ApprovalRequired,
 	}
 	groupTraitOptions := []rs.GroupTraitOption{rs.WithGroupProfile(profile)}
 	resource, err := rs.NewGroupResource(
@@ -144,6 +176,255 @@ func ConvertPageToken(token string) (int, error) {
 	return strconv.Atoi(token)
 }
 
+func departmentResource(ctx context.Context, department *client.Department, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"department_id":   department.ID,
+		"department_name": department.Name,
+		"head_user_id":    department.HeadUserID,
+		"prime_user_id":   department.PrimeUserID,
+	}
+
+	resource, err := rs.NewGroupResource(
+		department.Name,
+		departmentResourceType,
+		department.ID,
+		[]rs.GroupTraitOption{rs.WithGroupProfile(profile)},
+		rs.WithDescription(department.Description),
+		rs.WithParentResourceID(parentResourceID),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resource, nil
+}
+
+// departmentIDsProfile converts department IDs to a list that can be stored in a resource profile.
+func departmentIDsProfile(departmentIDs []int64) []interface{} {
+	rv := make([]interface{}, 0, len(departmentIDs))
+	for _, id := range departmentIDs {
+		rv = append(rv, id)
+	}
+	return rv
+}
+
+// profileDepartmentIDs returns the department IDs stored in a user's profile by departmentIDsProfile.
+func profileDepartmentIDs(resource *v2.Resource) ([]int64, error) {
+	trait, err := rs.GetUserTrait(resource)
+	if err != nil {
+		return nil, err
+	}
+
+	var rv []int64
+	for _, value := range trait.GetProfile().GetFields()["department_ids"].GetListValue().GetValues() {
+		rv = append(rv, int64(value.GetNumberValue()))
+	}
+	return rv, nil
+}
+
+// locationResource creates a location resource. Locations are parented by their parent location, if any.
+func locationResource(ctx context.Context, location *client.Location) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"location_id":   location.ID,
+		"location_name": location.Name,
+	}
+	if location.PrimaryContactID != nil {
+		profile["primary_contact_id"] = *location.PrimaryContactID
+	}
+	if location.Address != nil {
+		profile["city"] = location.Address.City
+		profile["state"] = location.Address.State
+		profile["country"] = location.Address.Country
+	}
+
+	var parentResourceID *v2.ResourceId
+	if location.ParentLocationID != nil {
+		profile["parent_location_id"] = *location.ParentLocationID
+		parentResourceID = &v2.ResourceId{
+			ResourceType: locationResourceType.Id,
+			Resource:     strconv.FormatInt(*location.ParentLocationID, 10),
+		}
+	}
+
+	resource, err := rs.NewGroupResource(
+		location.Name,
+		locationResourceType,
+		location.ID,
+		[]rs.GroupTraitOption{rs.WithGroupProfile(profile)},
+		rs.WithParentResourceID(parentResourceID),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resource, nil
+}
+
+// profileLocationID returns the location ID stored in a user's profile, if the user has a location.
+func profileLocationID(resource *v2.Resource) (*int64, error) {
+	trait, err := rs.GetUserTrait(resource)
+	if err != nil {
+		return nil, err
+	}
+
+	locationID, ok := rs.GetProfileInt64Value(trait.GetProfile(), "location_id")
+	if !ok {
+		return nil, nil
+	}
+	return &locationID, nil
+}
+
+// userPrincipals resolves Freshservice user IDs, as found on departments, applications and assets, to agent or requester
+// resource IDs. Agents and requesters share IDs, so a user is a requester when no agent has its ID.
+type userPrincipals struct {
+	client *client.FreshServiceClient
+
+	// agents are the IDs of the active and deactivated agents, listed once per sync.
+	mu     sync.Mutex
+	agents map[int64]struct{}
+}
+
+func newUserPrincipals(c *client.FreshServiceClient) *userPrincipals {
+	return &userPrincipals{client: c}
+}
+
+// reset makes the next lookup list the agents again.
+func (u *userPrincipals) reset() {
+	u.mu.Lock()
+	u.agents = nil
+	u.mu.Unlock()
+}
+
+// resourceID returns the resource ID of the agent or requester with userID.
+func (u *userPrincipals) resourceID(ctx context.Context, userID int64) (*v2.ResourceId, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.agents == nil {
+		agents, err := listAgentIDs(ctx, u.client)
+		if err != nil {
+			return nil, err
+		}
+		u.agents = agents
+	}
+
+	id := strconv.FormatInt(userID, 10)
+	if _, ok := u.agents[userID]; ok {
+		return &v2.ResourceId{ResourceType: agentUserResourceType.Id, Resource: id}, nil
+	}
+	return &v2.ResourceId{ResourceType: requesterResourceType.Id, Resource: id}, nil
+}
+
+// listAgentIDs returns the IDs of the active and the deactivated agents.
+func listAgentIDs(ctx context.Context, c *client.FreshServiceClient) (map[int64]struct{}, error) {
+	rv := make(map[int64]struct{})
+	for _, active := range []bool{true, false} {
+		for page, err := range c.AgentUserPages(ctx, client.WithActive(active)) {
+			if err != nil {
+				return nil, err
+			}
+			for _, agent := range page.Agents {
+				rv[agent.ID] = struct{}{}
+			}
+		}
+	}
+	return rv, nil
+}
+
+func applicationResource(ctx context.Context, application *client.Application) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"application_id":   application.ID,
+		"application_name": application.Name,
+		"application_type": application.ApplicationType,
+		"status":           application.Status,
+		"category":         application.Category,
+		"user_count":       application.UserCount,
+		"workspace_id":     application.WorkspaceID,
+	}
+	if application.ManagedByID != nil {
+		profile["managed_by_id"] = *application.ManagedByID
+	}
+
+	resource, err := rs.NewAppResource(
+		application.Name,
+		applicationResourceType,
+		application.ID,
+		[]rs.AppTraitOption{rs.WithAppProfile(profile)},
+		rs.WithDescription(application.Description),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resource, nil
+}
+
+// assetTypeResource creates an asset type resource, the parent of its assets.
+func assetTypeResource(ctx context.Context, assetType *client.AssetType) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"asset_type_id":   assetType.ID,
+		"asset_type_name": assetType.Name,
+		"visible":         assetType.Visible,
+	}
+	if assetType.ParentAssetTypeID != nil {
+		profile["parent_asset_type_id"] = *assetType.ParentAssetTypeID
+	}
+
+	resource, err := rs.NewResource(
+		assetType.Name,
+		assetTypeResourceType,
+		assetType.ID,
+		rs.WithDescription(assetType.Description),
+		rs.WithResourceProfile(profile),
+		rs.WithAnnotation(&v2.ChildResourceType{ResourceTypeId: assetResourceType.Id}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resource, nil
+}
+
+// assetResource creates an asset resource, identified by its display ID as the assets API is.
+func assetResource(ctx context.Context, asset *client.Asset, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"asset_id":      asset.ID,
+		"display_id":    asset.DisplayID,
+		"asset_name":    asset.Name,
+		"asset_tag":     asset.AssetTag,
+		"asset_type_id": asset.AssetTypeID,
+		"impact":        asset.Impact,
+		"workspace_id":  asset.WorkspaceID,
+	}
+	for key, value := range map[string]*int64{"user_id": asset.UserID, "agent_id": asset.AgentID, "group_id": asset.GroupID} {
+		if value != nil {
+			profile[key] = *value
+		}
+	}
+
+	resource, err := rs.NewResource(
+		asset.Name,
+		assetResourceType,
+		asset.DisplayID,
+		rs.WithDescription(asset.Description),
+		rs.WithResourceProfile(profile),
+		rs.WithParentResourceID(parentResourceID),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resource, nil
+}
+
+// entitlementSlug returns the slug of an entitlement, falling back to the last part of its ID
+// (resource_type:resource_id:slug) when the slug isn't set.
+func entitlementSlug(entitlement *v2.Entitlement) string {
+	if entitlement.Slug != "" {
+		return entitlement.Slug
+	}
+	return entitlement.Id[strings.LastIndex(entitlement.Id, ":")+1:]
+}
+
 func roleResource(ctx context.Context, role *client.Roles, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
 	profile := map[string]interface{}{
 		"id":          role.ID,
@@ -193,6 +474,9 @@ func requesterGroupResource(ctx context.Context, requesterGroup *client.Requeste
 		"requester_group_name": requesterGroup.Name,
 		"requester_group_type": requesterGroup.Type,
 	}
+	if requesterGroup.Rules != nil {
+		profile["requester_group_rules"] = requesterGroup.Rules
+	}
 	groupTraitOptions := []rs.GroupTraitOption{rs.WithGroupProfile(profile)}
 	resource, err := rs.NewGroupResource(
 		requesterGroup.Name,
@@ -207,3 +491,27 @@ func requesterGroupResource(ctx context.Context, requesterGroup *client.Requeste
 
 	return resource, nil
 }
+
+func workspaceResource(ctx context.Context, workspace *client.Workspace) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"workspace_id":   workspace.ID,
+		"workspace_name": workspace.Name,
+		"state":          workspace.State,
+		"primary":        workspace.Primary,
+		"restricted":     workspace.Restricted,
+	}
+
+	resource, err := rs.NewResource(
+		workspace.Name,
+		workspaceResourceType,
+		workspace.ID,
+		rs.WithDescription(workspace.Description),
+		rs.WithResourceProfile(profile),
+		rs.WithAnnotation(&v2.ChildResourceType{ResourceTypeId: agentGroupResourceType.Id}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resource, nil
+}
@@ -0,0 +1,359 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/conductorone/baton-freshservice/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+const (
+	fullSyncMode        = "full"
+	incrementalSyncMode = "incremental"
+
+	// updatedSinceOverlap is subtracted from the last sync time so clock skew between us and Freshservice
+	// can't drop an update. Records fetched twice are simply merged again.
+	updatedSinceOverlap = time.Minute
+)
+
+// userSnapshot is the set of users seen by the last sync of a user resource type.
+type userSnapshot[T any] struct {
+	LastFullSync time.Time   `json:"last_full_sync"`
+	LastSync     time.Time   `json:"last_sync"`
+	Records      map[int64]T `json:"records"`
+}
+
+type incrementalState struct {
+	Agents     *userSnapshot[client.Agent]      `json:"agents,omitempty"`
+	Requesters *userSnapshot[client.Requesters] `json:"requesters,omitempty"`
+}
+
+// incrementalStore keeps agents and requesters in a file between syncs, so that a sync only has to fetch the
+// users updated since the previous one. Deletions are only visible to full syncs, which run every fullSyncInterval.
+type incrementalStore struct {
+	path             string
+	fullSyncInterval time.Duration
+	now              func() time.Time
+
+	mu    sync.Mutex
+	state incrementalState
+}
+
+func newIncrementalStore(path string, fullSyncInterval time.Duration) (*incrementalStore, error) {
+	s := &incrementalStore{
+		path:             path,
+		fullSyncInterval: fullSyncInterval,
+		now:              time.Now,
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("freshservice-connector: reading incremental sync state: %w", err)
+	}
+	err = json.Unmarshal(data, &s.state)
+	if err != nil {
+		return nil, fmt.Errorf("freshservice-connector: parsing incremental sync state %s: %w", path, err)
+	}
+
+	return s, nil
+}
+
+// save writes the state to a temporary file first, so an interrupted write never leaves a truncated state behind.
+// The caller must hold s.mu.
+func (s *incrementalStore) save() error {
+	data, err := json.Marshal(&s.state)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("freshservice-connector: saving incremental sync state: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("freshservice-connector: saving incremental sync state: %w", err)
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// incrementalRun is the in-progress sync of one user resource type.
+type incrementalRun[T any] struct {
+	mode      string
+	startedAt time.Time
+	// complete is false when the run was picked up mid-way, e.g. after a restart, and so can't replace the snapshot.
+	complete bool
+	records  map[int64]T
+	ids      []int64
+}
+
+// incrementalLister lists the users of one resource type either from Freshservice (full mode) or from the
+// snapshot merged with the users updated since the last sync (incremental mode).
+type incrementalLister[T any] struct {
+	store        *incrementalStore
+	resourceType *v2.ResourceType
+	snapshot     func(*incrementalState) **userSnapshot[T]
+	id           func(*T) int64
+	// listPage lists the page after token, which is "" for the first page.
+	listPage func(ctx context.Context, pageSize int, token string, reqOpts ...client.ReqOpt) ([]T, string, annotations.Annotations, error)
+
+	run *incrementalRun[T]
+}
+
+func (l *incrementalLister[T]) list(ctx context.Context, pToken *pagination.Token) ([]T, string, annotations.Annotations, error) {
+	bag := &pagination.Bag{}
+	err := bag.Unmarshal(pToken.Token)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if bag.Current() == nil {
+		bag.Push(pagination.PageState{ResourceTypeID: l.resourceType.Id})
+	}
+	page := bag.PageToken()
+
+	mode := bag.ResourceID()
+	if mode == "" {
+		mode = l.chooseMode()
+		bag.Pop()
+		bag.Push(pagination.PageState{ResourceTypeID: l.resourceType.Id, ResourceID: mode})
+		ctxzap.Extract(ctx).Info("freshservice-connector: starting user sync",
+			zap.String("resource_type", l.resourceType.Id),
+			zap.String("mode", mode),
+		)
+	}
+
+	var (
+		records       []T
+		nextPageToken string
+		annos         annotations.Annotations
+	)
+	switch mode {
+	case fullSyncMode:
+		records, nextPageToken, annos, err = l.listFull(ctx, pToken.Size, page)
+	case incrementalSyncMode:
+		var offset int
+		offset, err = ConvertPageToken(page)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		records, nextPageToken, annos, err = l.listIncremental(ctx, pToken.Size, offset)
+	default:
+		err = fmt.Errorf("freshservice-connector: unknown sync mode %q", mode)
+	}
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	err = bag.Next(nextPageToken)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	nextPageToken, err = bag.Marshal()
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return records, nextPageToken, annos, nil
+}
+
+func (l *incrementalLister[T]) chooseMode() string {
+	l.store.mu.Lock()
+	defer l.store.mu.Unlock()
+
+	snapshot := *l.snapshot(&l.store.state)
+	if snapshot == nil || l.store.now().Sub(snapshot.LastFullSync) >= l.store.fullSyncInterval {
+		return fullSyncMode
+	}
+	return incrementalSyncMode
+}
+
+// listFull lists a page from Freshservice and records it, replacing the snapshot after the last page.
+func (l *incrementalLister[T]) listFull(ctx context.Context, pageSize int, page string) ([]T, string, annotations.Annotations, error) {
+	l.store.mu.Lock()
+	if l.run == nil || l.run.mode != fullSyncMode || page == "" {
+		l.run = &incrementalRun[T]{
+			mode:      fullSyncMode,
+			startedAt: l.store.now(),
+			complete:  page == "",
+			records:   make(map[int64]T),
+		}
+	}
+	run := l.run
+	l.store.mu.Unlock()
+
+	records, nextPage, annos, err := l.listPage(ctx, pageSize, page)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	l.store.mu.Lock()
+	defer l.store.mu.Unlock()
+	for i := range records {
+		run.records[l.id(&records[i])] = records[i]
+	}
+	if nextPage == "" && run.complete {
+		*l.snapshot(&l.store.state) = &userSnapshot[T]{
+			LastFullSync: run.startedAt,
+			LastSync:     run.startedAt,
+			Records:      run.records,
+		}
+		err = l.store.save()
+		if err != nil {
+			return nil, "", nil, err
+		}
+	}
+
+	return records, nextPage, annos, nil
+}
+
+// listIncremental serves a page of the snapshot merged with the users updated since the last sync,
+// and saves the merged snapshot after the last page.
+func (l *incrementalLister[T]) listIncremental(ctx context.Context, pageSize int, offset int) ([]T, string, annotations.Annotations, error) {
+	l.store.mu.Lock()
+	run := l.run
+	l.store.mu.Unlock()
+
+	var annos annotations.Annotations
+	if run == nil || run.mode != incrementalSyncMode || offset == 0 {
+		var err error
+		run, annos, err = l.fetchUpdates(ctx)
+		if err != nil {
+			return nil, "", nil, err
+		}
+	}
+
+	if pageSize <= 0 || pageSize > client.ItemsPerPage {
+		pageSize = client.ItemsPerPage
+	}
+	start := min(offset, len(run.ids))
+	end := min(start+pageSize, len(run.ids))
+	records := make([]T, 0, end-start)
+	for _, id := range run.ids[start:end] {
+		records = append(records, run.records[id])
+	}
+
+	if end < len(run.ids) {
+		return records, strconv.Itoa(end), annos, nil
+	}
+
+	l.store.mu.Lock()
+	defer l.store.mu.Unlock()
+	snapshot := *l.snapshot(&l.store.state)
+	if snapshot != nil {
+		snapshot.LastSync = run.startedAt
+		snapshot.Records = run.records
+		err := l.store.save()
+		if err != nil {
+			return nil, "", nil, err
+		}
+	}
+
+	return records, "", annos, nil
+}
+
+// fetchUpdates merges the users updated since the last sync into a copy of the snapshot and starts a run over it.
+func (l *incrementalLister[T]) fetchUpdates(ctx context.Context) (*incrementalRun[T], annotations.Annotations, error) {
+	l.store.mu.Lock()
+	snapshot := *l.snapshot(&l.store.state)
+	if snapshot == nil {
+		l.store.mu.Unlock()
+		return nil, nil, fmt.Errorf("freshservice-connector: incremental sync state for %s is missing, a full sync is required", l.resourceType.Id)
+	}
+	run := &incrementalRun[T]{
+		mode:      incrementalSyncMode,
+		startedAt: l.store.now(),
+		records:   maps.Clone(snapshot.Records),
+	}
+	since := snapshot.LastSync.Add(-updatedSinceOverlap)
+	l.store.mu.Unlock()
+
+	var (
+		annos   annotations.Annotations
+		page    string
+		updated int
+	)
+	for {
+		records, nextPage, pageAnnos, err := l.listPage(ctx, client.ItemsPerPage, page, client.WithUpdatedSince(since))
+		if err != nil {
+			return nil, nil, err
+		}
+		annos = pageAnnos
+		for i := range records {
+			run.records[l.id(&records[i])] = records[i]
+		}
+		updated += len(records)
+		if nextPage == "" {
+			break
+		}
+		page = nextPage
+	}
+	run.ids = slices.Sorted(maps.Keys(run.records))
+
+	ctxzap.Extract(ctx).Debug("freshservice-connector: fetched updated users",
+		zap.String("resource_type", l.resourceType.Id),
+		zap.Time("updated_since", since),
+		zap.Int("updated", updated),
+		zap.Int("total", len(run.ids)),
+	)
+
+	l.store.mu.Lock()
+	l.run = run
+	l.store.mu.Unlock()
+
+	return run, annos, nil
+}
+
+func newAgentLister(store *incrementalStore, c *client.FreshServiceClient) *incrementalLister[client.Agent] {
+	return &incrementalLister[client.Agent]{
+		store:        store,
+		resourceType: agentUserResourceType,
+		snapshot:     func(s *incrementalState) **userSnapshot[client.Agent] { return &s.Agents },
+		id:           func(a *client.Agent) int64 { return a.ID },
+		listPage: func(ctx context.Context, pageSize int, token string, reqOpts ...client.ReqOpt) ([]client.Agent, string, annotations.Annotations, error) {
+			return listAgentPage(ctx, c, pageSize, token, reqOpts...)
+		},
+	}
+}
+
+func newRequesterLister(store *incrementalStore, c *client.FreshServiceClient) *incrementalLister[client.Requesters] {
+	return &incrementalLister[client.Requesters]{
+		store:        store,
+		resourceType: requesterResourceType,
+		snapshot:     func(s *incrementalState) **userSnapshot[client.Requesters] { return &s.Requesters },
+		id:           func(r *client.Requesters) int64 { return r.ID },
+		listPage: func(ctx context.Context, pageSize int, token string, reqOpts ...client.ReqOpt) ([]client.Requesters, string, annotations.Annotations, error) {
+			page, err := ConvertPageToken(token)
+			if err != nil {
+				return nil, "", nil, err
+			}
+			users, nextPage, annos, err := c.ListRequesterUsers(ctx, client.PageOptions{PerPage: pageSize, Page: page}, reqOpts...)
+			if err != nil {
+				return nil, "", nil, err
+			}
+			return users.Requesters, nextPage, annos, nil
+		},
+	}
+}
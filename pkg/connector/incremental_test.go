@@ -0,0 +1,85 @@
+package connector
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/conductorone/baton-freshservice/pkg/client"
+	"github.com/conductorone/baton-freshservice/pkg/client/clienttest"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
+	"github.com/stretchr/testify/require"
+)
+
+// incrementalSyncers returns the agent and requester syncers of a connector with incremental sync enabled, sharing
+// the store at statePath and a clock returning *now.
+func incrementalSyncers(t *testing.T, c *client.FreshServiceClient, statePath string, now *time.Time) (connectorbuilder.ResourceSyncer, connectorbuilder.ResourceSyncer) {
+	t.Helper()
+	conn, err := New(ctxTest, "", "", c, WithIncrementalSync(statePath, 24*time.Hour))
+	require.NoError(t, err)
+	conn.incremental.now = func() time.Time { return *now }
+
+	syncers := conn.ResourceSyncers(ctxTest)
+	return syncers[0], syncers[1]
+}
+
+func updatedSinceRequests(srv *clienttest.Server) []string {
+	var rv []string
+	for _, req := range srv.Requests() {
+		if strings.Contains(req, "updated_since=") {
+			rv = append(rv, req)
+		}
+	}
+	return rv
+}
+
+func requesterNames(resources []*v2.Resource) []string {
+	names := make([]string, 0, len(resources))
+	for _, r := range resources {
+		names = append(names, r.DisplayName)
+	}
+	return names
+}
+
+func TestIncrementalSync(t *testing.T) {
+	srv, c := newTestTenant(t)
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	agents, requesters := incrementalSyncers(t, c, statePath, &now)
+	require.Equal(t, []string{"1", "2", "3"}, resourceIDs(listAll(t, agents, nil, 2)))
+	require.Equal(t, []string{"101", "102", "103"}, resourceIDs(listAll(t, requesters, nil, 2)))
+	require.Empty(t, updatedSinceRequests(srv))
+
+	// A new process picks up the saved state and only fetches what changed since the last sync.
+	now = now.Add(time.Hour)
+	srv.AddRequester(client.Requesters{ID: 102, Active: true, FirstName: "Samantha", PrimaryEmail: "sam@example.com", UpdatedAt: now.Add(-time.Minute)})
+	srv.AddRequester(client.Requesters{ID: 104, Active: true, FirstName: "Uma", PrimaryEmail: "uma@example.com", UpdatedAt: now.Add(-time.Minute)})
+	srv.RemoveRequester(101)
+	_, requesters = incrementalSyncers(t, c, statePath, &now)
+	listed := listAll(t, requesters, nil, 2)
+	require.Equal(t, []string{"101", "102", "103", "104"}, resourceIDs(listed))
+	require.Equal(t, "Samantha", listed[1].DisplayName)
+	require.Len(t, updatedSinceRequests(srv), 1)
+	require.Contains(t, updatedSinceRequests(srv)[0], "updated_since=2024-05-01T11%3A59%3A00Z")
+
+	// Once the full sync interval has passed, every requester is listed again and deletions show up.
+	now = now.Add(24 * time.Hour)
+	_, requesters = incrementalSyncers(t, c, statePath, &now)
+	listed = listAll(t, requesters, nil, 2)
+	require.Equal(t, []string{"102", "103", "104"}, resourceIDs(listed))
+	require.Equal(t, []string{"Samantha", "Tia", "Uma"}, requesterNames(listed))
+	require.Len(t, updatedSinceRequests(srv), 1)
+}
+
+func TestIncrementalSyncRejectsCorruptState(t *testing.T) {
+	_, c := newTestTenant(t)
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	require.NoError(t, os.WriteFile(statePath, []byte("{"), 0o600))
+
+	_, err := New(ctxTest, "", "", c, WithIncrementalSync(statePath, time.Hour))
+	require.Error(t, err)
+}
@@ -0,0 +1,193 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/conductorone/baton-freshservice/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+type locationBuilder struct {
+	resourceType *v2.ResourceType
+	client       *client.FreshServiceClient
+}
+
+func (l *locationBuilder) ResourceType(_ context.Context) *v2.ResourceType {
+	return locationResourceType
+}
+
+// List returns all the locations. The locations API can't filter by parent, so every location is listed once
+// here with its parent location set as its parent resource.
+func (l *locationBuilder) List(ctx context.Context, _ *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	var rv []*v2.Resource
+	bag, pageToken, err := getToken(pToken, locationResourceType)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	locations, nextPageToken, annotation, err := l.client.ListLocations(ctx, client.PageOptions{
+		PerPage: pToken.Size,
+		Page:    pageToken,
+	})
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	err = bag.Next(nextPageToken)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	for _, location := range locations.Locations {
+		locationCopy := location
+		lr, err := locationResource(ctx, &locationCopy)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		rv = append(rv, lr)
+	}
+
+	nextPageToken, err = bag.Marshal()
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return rv, nextPageToken, annotation, nil
+}
+
+func (l *locationBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return []*v2.Entitlement{
+		ent.NewAssignmentEntitlement(resource, assignedEntitlement,
+			ent.WithGrantableTo(agentUserResourceType, requesterResourceType),
+			ent.WithDescription(fmt.Sprintf("Assigned to %s location in FreshService", resource.DisplayName)),
+			ent.WithDisplayName(fmt.Sprintf("%s Location %s", resource.DisplayName, assignedEntitlement)),
+		),
+	}, "", nil, nil
+}
+
+// Grants always returns an empty slice for locations. Assignments are granted by the users themselves,
+// as only users know their location.
+func (l *locationBuilder) Grants(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// userLocationGrants returns the location assignment grant of a user, if the user has a location.
+func userLocationGrants(ctx context.Context, userID *v2.ResourceId, locationID *int64) ([]*v2.Grant, error) {
+	if locationID == nil {
+		return nil, nil
+	}
+
+	location, err := locationResource(ctx, &client.Location{ID: *locationID})
+	if err != nil {
+		return nil, err
+	}
+	return []*v2.Grant{grant.NewGrant(location, assignedEntitlement, userID)}, nil
+}
+
+// Grant assigns the user to the location, replacing the location the user was assigned to.
+func (l *locationBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
+	err := checkLocationProvisioning(ctx, principal)
+	if err != nil {
+		return nil, err
+	}
+
+	locationID, err := strconv.ParseInt(entitlement.Resource.Id.Resource, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := l.currentLocation(ctx, principal.Id)
+	if err != nil {
+		return nil, err
+	}
+	if current != nil && *current == locationID {
+		ctxzap.Extract(ctx).Info(
+			"freshservice-connector: user is already assigned to the location",
+			zap.String("principal_id", principal.Id.Resource),
+			zap.Int64("location_id", locationID),
+		)
+		return annotations.New(&v2.GrantAlreadyExists{}), nil
+	}
+
+	return l.updateLocation(ctx, principal.Id, &locationID)
+}
+
+// Revoke clears the location of the user, unless the user has since been assigned to another location.
+func (l *locationBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	err := checkLocationProvisioning(ctx, grant.Principal)
+	if err != nil {
+		return nil, err
+	}
+
+	locationID, err := strconv.ParseInt(grant.Entitlement.Resource.Id.Resource, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := l.currentLocation(ctx, grant.Principal.Id)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil || *current != locationID {
+		ctxzap.Extract(ctx).Info(
+			"freshservice-connector: user is no longer assigned to the location",
+			zap.String("principal_id", grant.Principal.Id.Resource),
+			zap.Int64("location_id", locationID),
+		)
+		return annotations.New(&v2.GrantAlreadyRevoked{}), nil
+	}
+
+	return l.updateLocation(ctx, grant.Principal.Id, nil)
+}
+
+// checkLocationProvisioning rejects principals that can't be assigned to a location.
+func checkLocationProvisioning(ctx context.Context, principal *v2.Resource) error {
+	if principal.Id.ResourceType != agentUserResourceType.Id && principal.Id.ResourceType != requesterResourceType.Id {
+		ctxzap.Extract(ctx).Warn(
+			"freshservice-connector: only users can be assigned to a location",
+			zap.String("principal_type", principal.Id.ResourceType),
+			zap.String("principal_id", principal.Id.Resource),
+		)
+		return fmt.Errorf("freshservice-connector: only users can be assigned to a location")
+	}
+	return nil
+}
+
+// currentLocation returns the location the agent or requester is assigned to, if any.
+func (l *locationBuilder) currentLocation(ctx context.Context, userID *v2.ResourceId) (*int64, error) {
+	if userID.ResourceType == agentUserResourceType.Id {
+		agent, _, err := l.client.GetAgentDetail(ctx, userID.Resource)
+		if err != nil {
+			return nil, err
+		}
+		return agent.Agent.LocationID, nil
+	}
+
+	requester, _, err := l.client.GetRequesterDetail(ctx, userID.Resource)
+	if err != nil {
+		return nil, err
+	}
+	return requester.Requester.LocationID, nil
+}
+
+func (l *locationBuilder) updateLocation(ctx context.Context, userID *v2.ResourceId, locationID *int64) (annotations.Annotations, error) {
+	if userID.ResourceType == agentUserResourceType.Id {
+		return l.client.UpdateAgentLocation(ctx, locationID, userID.Resource)
+	}
+	return l.client.UpdateRequesterLocation(ctx, locationID, userID.Resource)
+}
+
+func newLocationBuilder(c *client.FreshServiceClient) *locationBuilder {
+	return &locationBuilder{
+		resourceType: locationResourceType,
+		client:       c,
+	}
+}
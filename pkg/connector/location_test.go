@@ -0,0 +1,95 @@
+package connector
+
+import (
+	"testing"
+
+	"github.com/conductorone/baton-freshservice/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocationsAreParentedByParentLocation(t *testing.T) {
+	srv, c := newTestTenant(t)
+	emea := int64(50)
+	srv.AddLocation(client.Location{ID: emea, Name: "EMEA"})
+	srv.AddLocation(client.Location{ID: 51, Name: "London", ParentLocationID: &emea,
+		Address: &client.LocationAddress{City: "London", Country: "UK"}})
+
+	locations := listAll(t, newLocationBuilder(c), nil, 1)
+	require.Equal(t, []string{"50", "51"}, resourceIDs(locations))
+	require.Nil(t, locations[0].ParentResourceId)
+	require.Equal(t, "location", locations[1].ParentResourceId.ResourceType)
+	require.Equal(t, "50", locations[1].ParentResourceId.Resource)
+}
+
+func TestUsersGrantLocationAssignment(t *testing.T) {
+	srv, c := newTestTenant(t)
+	london := int64(51)
+	srv.AddLocation(client.Location{ID: london, Name: "London"})
+	srv.AddAgent(client.Agent{ID: 2, Active: true, FirstName: "Bo", Email: "bo@example.com", LocationID: &london})
+	srv.AddRequester(client.Requesters{ID: 102, Active: true, FirstName: "Sam", PrimaryEmail: "sam@example.com", LocationID: &london})
+
+	requester, err := requesterUserResource(ctxTest, &client.Requesters{ID: 102, LocationID: &london}, nil)
+	require.NoError(t, err)
+	grants, _, _, err := newRequesterUserBuilder(c).Grants(ctxTest, requester, &pagination.Token{})
+	require.NoError(t, err)
+	require.Len(t, grants, 1)
+	require.Equal(t, "location:51:assigned:requester:102", grants[0].Id)
+
+	agent, err := agentResource(ctxTest, &client.Agent{ID: 2}, nil)
+	require.NoError(t, err)
+	grants, _, _, err = newAgentUserBuilder(c).Grants(ctxTest, agent, &pagination.Token{})
+	require.NoError(t, err)
+	require.Len(t, grants, 1)
+	require.Equal(t, "location:51:assigned:agent:2", grants[0].Id)
+}
+
+func TestLocationGrantAndRevoke(t *testing.T) {
+	srv, c := newTestTenant(t)
+	srv.AddLocation(client.Location{ID: 50, Name: "EMEA"})
+	srv.AddLocation(client.Location{ID: 51, Name: "London"})
+	l := newLocationBuilder(c)
+	emea, err := locationResource(ctxTest, &client.Location{ID: 50, Name: "EMEA"})
+	require.NoError(t, err)
+	london, err := locationResource(ctxTest, &client.Location{ID: 51, Name: "London"})
+	require.NoError(t, err)
+	requester, err := requesterUserResource(ctxTest, &client.Requesters{ID: 102}, nil)
+	require.NoError(t, err)
+	agent, err := agentResource(ctxTest, &client.Agent{ID: 3}, nil)
+	require.NoError(t, err)
+
+	_, err = l.Grant(ctxTest, agent, ent.NewAssignmentEntitlement(london, assignedEntitlement))
+	require.NoError(t, err)
+	updatedAgent, _ := srv.Agent(3)
+	require.Equal(t, int64(51), *updatedAgent.LocationID)
+	annos, err := l.Grant(ctxTest, agent, ent.NewAssignmentEntitlement(london, assignedEntitlement))
+	require.NoError(t, err)
+	require.True(t, annos.Contains(&v2.GrantAlreadyExists{}))
+	require.Equal(t, 1, countRequests(srv, "PUT /agents/3"))
+
+	_, err = l.Grant(ctxTest, requester, ent.NewAssignmentEntitlement(emea, assignedEntitlement))
+	require.NoError(t, err)
+	_, err = l.Grant(ctxTest, requester, ent.NewAssignmentEntitlement(london, assignedEntitlement))
+	require.NoError(t, err)
+	updated, _ := srv.Requester(102)
+	require.Equal(t, int64(51), *updated.LocationID)
+
+	// The requester moved to London, so revoking EMEA leaves the location alone.
+	_, err = l.Revoke(ctxTest, grant.NewGrant(emea, assignedEntitlement, requester))
+	require.NoError(t, err)
+	updated, _ = srv.Requester(102)
+	require.Equal(t, int64(51), *updated.LocationID)
+
+	_, err = l.Revoke(ctxTest, grant.NewGrant(london, assignedEntitlement, requester))
+	require.NoError(t, err)
+	updated, _ = srv.Requester(102)
+	require.Nil(t, updated.LocationID)
+
+	group, err := agentGroupResource(ctxTest, &client.AgentGroup{ID: 20}, nil)
+	require.NoError(t, err)
+	_, err = l.Grant(ctxTest, group, ent.NewAssignmentEntitlement(london, assignedEntitlement))
+	require.Error(t, err)
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/conductorone/baton-sdk/pkg/pagination"
 	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
 	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
 	"go.uber.org/zap"
 )
@@ -61,18 +62,57 @@ func (rg *requesterGroupBuilder) List(ctx context.Context, parentResourceID *v2.
 	return rv, nextPageToken, annotation, nil
 }
 
+// Entitlements returns the membership of a requester group. The members of rule-based groups are managed by their
+// rules, so their membership is marked immutable.
 func (rg *requesterGroupBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
 	var rv []*v2.Entitlement
+	description := fmt.Sprintf("Access to %s requester group in FreshService", resource.DisplayName)
+	ruleBased := isRuleBased(resource)
+	if ruleBased {
+		description = fmt.Sprintf("Access to %s rule-based requester group in FreshService, managed by the group's rules", resource.DisplayName)
+	}
 	options := []ent.EntitlementOption{
 		ent.WithGrantableTo(requesterResourceType),
-		ent.WithDescription(fmt.Sprintf("Access to %s requester group in FreshService", resource.DisplayName)),
+		ent.WithDescription(description),
 		ent.WithDisplayName(fmt.Sprintf("%s Requester Group %s", resource.DisplayName, memberEntitlement)),
 	}
+	if ruleBased {
+		options = append(options, ent.WithAnnotation(&v2.EntitlementImmutable{}))
+	}
 	rv = append(rv, ent.NewAssignmentEntitlement(resource, memberEntitlement, options...))
 
 	return rv, "", nil, nil
 }
 
+// isRuleBased reports whether the members of the requester group are managed by its rules.
+func isRuleBased(resource *v2.Resource) bool {
+	trait, err := rs.GetGroupTrait(resource)
+	if err != nil {
+		return false
+	}
+	groupType, ok := trait.GetProfile().GetFields()["requester_group_type"]
+	return ok && groupType.GetStringValue() == client.RequesterGroupTypeRuleBased
+}
+
+// checkRequesterGroupProvisioning rejects membership changes to rule-based requester groups. The group is looked up
+// since it may have become rule-based since it was synced.
+func (rg *requesterGroupBuilder) checkRequesterGroupProvisioning(ctx context.Context, requesterGroupId string) error {
+	requesterGroup, _, err := rg.client.GetRequesterGroup(ctx, requesterGroupId)
+	if err != nil {
+		return err
+	}
+
+	if requesterGroup.RequesterGroup.Type == client.RequesterGroupTypeRuleBased {
+		ctxzap.Extract(ctx).Warn(
+			"freshservice-connector: members of rule-based requester groups are managed by the group's rules",
+			zap.String("requester_group_id", requesterGroupId),
+		)
+		return fmt.Errorf("freshservice-connector: requester group %s is rule-based, its members are managed by the group's rules", requesterGroupId)
+	}
+
+	return nil
+}
+
 func (rg *requesterGroupBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
 	var (
 		rv []*v2.Grant
@@ -104,7 +144,12 @@ func (rg *requesterGroupBuilder) Grants(ctx context.Context, resource *v2.Resour
 		rv = append(rv, gr)
 	}
 
-	return rv, "", annotation, nil
+	nextPageToken, err = bag.Marshal()
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return rv, nextPageToken, annotation, nil
 }
 
 func (rg *requesterGroupBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
@@ -120,6 +165,24 @@ func (rg *requesterGroupBuilder) Grant(ctx context.Context, principal *v2.Resour
 
 	requesterGroupId := entitlement.Resource.Id.Resource
 	requesterId := principal.Id.Resource
+	err := rg.checkRequesterGroupProvisioning(ctx, requesterGroupId)
+	if err != nil {
+		return nil, err
+	}
+
+	member, err := rg.isMember(ctx, requesterGroupId, requesterId)
+	if err != nil {
+		return nil, err
+	}
+	if member {
+		l.Info(
+			"freshservice-connector: requester is already a member of the requester group",
+			zap.String("requester_group_id", requesterGroupId),
+			zap.String("principal_id", requesterId),
+		)
+		return annotations.New(&v2.GrantAlreadyExists{}), nil
+	}
+
 	annotation, err := rg.client.AddRequesterToRequesterGroup(ctx, requesterGroupId, requesterId)
 	if err != nil {
 		return nil, err
@@ -144,17 +207,62 @@ func (rg *requesterGroupBuilder) Revoke(ctx context.Context, grant *v2.Grant) (a
 
 	requesterId := principal.Id.Resource
 	requesterGroupId := entitlement.Resource.Id.Resource
+	err := rg.checkRequesterGroupProvisioning(ctx, requesterGroupId)
+	if err != nil {
+		if client.IsNotFound(err) {
+			return rg.alreadyRevoked(ctx, requesterGroupId, requesterId), nil
+		}
+		return nil, err
+	}
+
+	member, err := rg.isMember(ctx, requesterGroupId, requesterId)
+	if err != nil {
+		return nil, err
+	}
+	if !member {
+		return rg.alreadyRevoked(ctx, requesterGroupId, requesterId), nil
+	}
+
 	annotation, err := rg.client.DeleteRequesterFromRequesterGroup(ctx,
 		requesterGroupId,
 		requesterId,
 	)
 	if err != nil {
+		// The requester or the group was deleted since the membership was checked.
+		if client.IsNotFound(err) {
+			return rg.alreadyRevoked(ctx, requesterGroupId, requesterId), nil
+		}
 		return nil, err
 	}
 
 	return annotation, nil
 }
 
+// isMember reports whether the requester is a member of the requester group.
+func (rg *requesterGroupBuilder) isMember(ctx context.Context, requesterGroupId string, requesterId string) (bool, error) {
+	for page, err := range rg.client.RequesterGroupMemberPages(ctx, requesterGroupId) {
+		if err != nil {
+			return false, err
+		}
+		for _, requester := range page.Requesters {
+			if strconv.Itoa(requester.ID) == requesterId {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// alreadyRevoked logs and annotates the revoke of a membership that no longer exists.
+func (rg *requesterGroupBuilder) alreadyRevoked(ctx context.Context, requesterGroupId string, requesterId string) annotations.Annotations {
+	ctxzap.Extract(ctx).Info(
+		"freshservice-connector: requester is no longer a member of the requester group",
+		zap.String("requester_group_id", requesterGroupId),
+		zap.String("principal_id", requesterId),
+	)
+	return annotations.New(&v2.GrantAlreadyRevoked{})
+}
+
 func newRequesterGroupBuilder(c *client.FreshServiceClient) *requesterGroupBuilder {
 	return &requesterGroupBuilder{
 		resourceType: resourceTypeRequesterGroup,
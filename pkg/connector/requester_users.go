@@ -2,16 +2,26 @@ package connector
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/conductorone/baton-freshservice/pkg/client"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/actions"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
 	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 type requesterUserBuilder struct {
 	resourceType *v2.ResourceType
 	client       *client.FreshServiceClient
+	// lister is set when incremental sync is enabled.
+	lister *incrementalLister[client.Requesters]
+	// forget makes Delete permanently delete requesters instead of deactivating them.
+	forget bool
 }
 
 func (u *requesterUserBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
@@ -21,7 +31,35 @@ func (u *requesterUserBuilder) ResourceType(ctx context.Context) *v2.ResourceTyp
 // List returns all the users from the database as resource objects.
 // Users include a UserTrait because they are the 'shape' of a standard user.
 func (u *requesterUserBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
-	var rv []*v2.Resource
+	var (
+		users         []client.Requesters
+		nextPageToken string
+		annotation    annotations.Annotations
+		err           error
+	)
+	if u.lister != nil {
+		users, nextPageToken, annotation, err = u.lister.list(ctx, pToken)
+	} else {
+		users, nextPageToken, annotation, err = u.listPage(ctx, pToken)
+	}
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	rv := make([]*v2.Resource, 0, len(users))
+	for _, user := range users {
+		userCopy := user
+		ur, err := requesterUserResource(ctx, &userCopy, nil)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		rv = append(rv, ur)
+	}
+
+	return rv, nextPageToken, annotation, nil
+}
+
+func (u *requesterUserBuilder) listPage(ctx context.Context, pToken *pagination.Token) ([]client.Requesters, string, annotations.Annotations, error) {
 	bag, pageToken, err := getToken(pToken, requesterResourceType)
 	if err != nil {
 		return nil, "", nil, err
@@ -40,21 +78,12 @@ func (u *requesterUserBuilder) List(ctx context.Context, parentResourceID *v2.Re
 		return nil, "", nil, err
 	}
 
-	for _, user := range users.Requesters {
-		userCopy := user
-		ur, err := requesterUserResource(ctx, &userCopy, nil)
-		if err != nil {
-			return nil, "", nil, err
-		}
-		rv = append(rv, ur)
-	}
-
 	nextPageToken, err = bag.Marshal()
 	if err != nil {
 		return nil, "", nil, err
 	}
 
-	return rv, nextPageToken, annotation, nil
+	return users.Requesters, nextPageToken, annotation, nil
 }
 
 // Entitlements always returns an empty slice for users.
@@ -62,9 +91,185 @@ func (u *requesterUserBuilder) Entitlements(_ context.Context, resource *v2.Reso
 	return nil, "", nil, nil
 }
 
-// Grants always returns an empty slice for users since they don't have any entitlements.
+// Grants returns the department memberships and the location of a requester, read from its profile.
 func (u *requesterUserBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
-	return nil, "", nil, nil
+	departmentIDs, err := profileDepartmentIDs(resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	rv, err := userDepartmentGrants(ctx, resource.Id, departmentIDs)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	locationID, err := profileLocationID(resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	locationGrants, err := userLocationGrants(ctx, resource.Id, locationID)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	rv = append(rv, locationGrants...)
+
+	return rv, "", nil, nil
+}
+
+// CreateAccount creates a requester from the account profile described by accountCreationSchema, or an agent when its
+// account_type is agent. Requesters are the only account manager of the connector, so that account creation requests
+// without a resource type reach it too. Users sign in through Freshservice's activation email, so no credentials are
+// returned.
+func (u *requesterUserBuilder) CreateAccount(
+	ctx context.Context,
+	accountInfo *v2.AccountInfo,
+	credentialOptions *v2.LocalCredentialOptions,
+) (connectorbuilder.CreateAccountResponse, []*v2.PlaintextData, annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+	userType, err := accountType(accountInfo.GetProfile())
+	if err != nil {
+		l.Warn("freshservice-connector: invalid account", zap.Error(err))
+		return nil, nil, nil, err
+	}
+	if userType == accountTypeAgent {
+		res, annotation, err := createAgentAccount(ctx, u.client, accountInfo)
+		return res, nil, annotation, err
+	}
+
+	requester, err := newCreateRequester(accountInfo)
+	if err != nil {
+		l.Warn("freshservice-connector: invalid requester account", zap.Error(err))
+		return nil, nil, nil, err
+	}
+
+	res, annotation, err := u.client.CreateRequester(ctx, requester)
+	if err != nil {
+		l.Warn(
+			"freshservice-connector: failed to create requester",
+			zap.String("email", requester.PrimaryEmail),
+			zap.Error(err),
+		)
+		return nil, nil, nil, fmt.Errorf("freshservice-connector: failed to create requester %s: %w", requester.PrimaryEmail, err)
+	}
+
+	resource, err := requesterUserResource(ctx, &res.Requester, nil)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return &v2.CreateAccountResponse_SuccessResult{
+		Resource:              resource,
+		IsCreateAccountResult: true,
+	}, nil, annotation, nil
+}
+
+// CreateAccountCapabilityDetails reports that requesters and agents are created without a password.
+func (u *requesterUserBuilder) CreateAccountCapabilityDetails(_ context.Context) (*v2.CredentialDetailsAccountProvisioning, annotations.Annotations, error) {
+	return &v2.CredentialDetailsAccountProvisioning{
+		SupportedCredentialOptions: []v2.CapabilityDetailCredentialOption{
+			v2.CapabilityDetailCredentialOption_CAPABILITY_DETAIL_CREDENTIAL_OPTION_NO_PASSWORD,
+		},
+		PreferredCredentialOption: v2.CapabilityDetailCredentialOption_CAPABILITY_DETAIL_CREDENTIAL_OPTION_NO_PASSWORD,
+	}, nil, nil
+}
+
+// newCreateRequester returns the requester described by an account profile.
+func newCreateRequester(accountInfo *v2.AccountInfo) (*client.CreateRequester, error) {
+	profile := accountInfo.GetProfile()
+	requester := &client.CreateRequester{
+		FirstName:    accountString(profile, "first_name"),
+		LastName:     accountString(profile, "last_name"),
+		PrimaryEmail: accountEmail(accountInfo),
+		JobTitle:     accountString(profile, "job_title"),
+	}
+	if requester.PrimaryEmail == "" {
+		return nil, fmt.Errorf("freshservice-connector: email is required to create a requester")
+	}
+	if requester.FirstName == "" {
+		return nil, fmt.Errorf("freshservice-connector: first_name is required to create a requester")
+	}
+	for _, key := range agentAccountFields {
+		if accountFieldSet(profile, key) {
+			return nil, fmt.Errorf("freshservice-connector: %s only applies to agents, set account_type to %s to create an agent", key, accountTypeAgent)
+		}
+	}
+
+	var err error
+	requester.DepartmentIDs, err = accountIDs(profile, "department_ids")
+	if err != nil {
+		return nil, err
+	}
+	requester.LocationID, err = accountID(profile, "location_id")
+	if err != nil {
+		return nil, err
+	}
+	requester.ReportingManagerID, err = accountID(profile, "reporting_manager_id")
+	if err != nil {
+		return nil, err
+	}
+
+	return requester, nil
+}
+
+// Delete deactivates the requester, or forgets it when the connector is configured to. Nothing is written when the
+// requester is already gone, or already inactive and only to be deactivated.
+func (u *requesterUserBuilder) Delete(ctx context.Context, resourceId *v2.ResourceId) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+	requesterId := resourceId.Resource
+	requester, _, err := u.client.GetRequesterDetail(ctx, requesterId)
+	switch {
+	case client.IsNotFound(err):
+		l.Info("freshservice-connector: requester is already deleted", zap.String("requester_id", requesterId))
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+
+	if u.forget {
+		annotation, err := u.client.ForgetRequester(ctx, requesterId)
+		if err != nil {
+			l.Warn(
+				"freshservice-connector: failed to forget requester",
+				zap.String("requester_id", requesterId),
+				zap.Error(err),
+			)
+			return nil, fmt.Errorf("freshservice-connector: failed to forget requester %s: %w", requesterId, err)
+		}
+		return annotation, nil
+	}
+
+	if !requester.Requester.Active {
+		l.Info("freshservice-connector: requester is already deactivated", zap.String("requester_id", requesterId))
+		return nil, nil
+	}
+	annotation, err := u.client.DeactivateRequester(ctx, requesterId)
+	if err != nil {
+		l.Warn(
+			"freshservice-connector: failed to deactivate requester",
+			zap.String("requester_id", requesterId),
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("freshservice-connector: failed to deactivate requester %s: %w", requesterId, err)
+	}
+
+	return annotation, nil
+}
+
+// ResourceActions registers the actions on requesters.
+func (u *requesterUserBuilder) ResourceActions(ctx context.Context, registry actions.ActionRegistry) error {
+	err := registry.Register(ctx, convertToAgentSchema, u.convertToAgent)
+	if err != nil {
+		return err
+	}
+	err = registry.Register(ctx, userStatusSchema(requesterResourceType, false), func(ctx context.Context, args *structpb.Struct) (*structpb.Struct, annotations.Annotations, error) {
+		return u.setStatus(ctx, args, false)
+	})
+	if err != nil {
+		return err
+	}
+	return registry.Register(ctx, userStatusSchema(requesterResourceType, true), func(ctx context.Context, args *structpb.Struct) (*structpb.Struct, annotations.Annotations, error) {
+		return u.setStatus(ctx, args, true)
+	})
 }
 
 func newRequesterUserBuilder(c *client.FreshServiceClient) *requesterUserBuilder {
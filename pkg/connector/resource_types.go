@@ -17,6 +17,13 @@ var (
 		DisplayName: "Requester",
 		Description: "Requester users of FreshService",
 		Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_USER},
+		// Requesters have no entitlements, but grant department membership and location assignment.
+		Annotations: annotations.New(&v2.SkipEntitlements{}),
+	}
+	workspaceResourceType = &v2.ResourceType{
+		Id:          "workspace",
+		DisplayName: "Workspace",
+		Description: "Workspaces of FreshService",
 		Annotations: annotations.New(&v2.SkipEntitlementsAndGrants{}),
 	}
 	agentGroupResourceType = &v2.ResourceType{
@@ -39,4 +46,33 @@ var (
 		Description: "Requester groups of FreshService",
 		Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_GROUP},
 	}
+	departmentResourceType = &v2.ResourceType{
+		Id:          "department",
+		DisplayName: "Department",
+		Description: "Departments of FreshService, or companies in MSP mode",
+		Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_GROUP},
+	}
+	locationResourceType = &v2.ResourceType{
+		Id:          "location",
+		DisplayName: "Location",
+		Description: "Locations of FreshService",
+		Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_GROUP},
+	}
+	assetTypeResourceType = &v2.ResourceType{
+		Id:          "asset_type",
+		DisplayName: "Asset Type",
+		Description: "Asset types of the FreshService CMDB",
+		Annotations: annotations.New(&v2.SkipEntitlementsAndGrants{}),
+	}
+	assetResourceType = &v2.ResourceType{
+		Id:          "asset",
+		DisplayName: "Asset",
+		Description: "Assets of the FreshService CMDB",
+	}
+	applicationResourceType = &v2.ResourceType{
+		Id:          "application",
+		DisplayName: "Application",
+		Description: "Software applications tracked by FreshService SaaS Management",
+		Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_APP},
+	}
 )
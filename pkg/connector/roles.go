@@ -3,13 +3,17 @@ package connector
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/conductorone/baton-freshservice/pkg/client"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/conductorone/baton-sdk/pkg/pagination"
 	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
 	"go.uber.org/zap"
 )
@@ -17,9 +21,23 @@ import (
 type roleBuilder struct {
 	resourceType *v2.ResourceType
 	client       *client.FreshServiceClient
+
+	// groups are the agent groups roles can be scoped to, looked up once per sync.
+	mu     sync.Mutex
+	groups []client.AgentGroup
 }
 
-const assignedEntitlement = "assigned"
+const (
+	assignedEntitlement = "assigned"
+
+	// Assignment scopes of an agent's role. The entire_helpdesk and member_groups scopes are also the slugs of their
+	// entitlements.
+	assignmentScopeEntireHelpdesk  = "entire_helpdesk"
+	assignmentScopeMemberGroups    = "member_groups"
+	assignmentScopeSpecifiedGroups = "specified_groups"
+
+	specifiedGroupEntitlementPrefix = "specified_group_"
+)
 
 func (r *roleBuilder) ResourceType(_ context.Context) *v2.ResourceType {
 	return resourceTypeRole
@@ -31,6 +49,12 @@ func (r *roleBuilder) List(ctx context.Context, _ *v2.ResourceId, pToken *pagina
 	if err != nil {
 		return nil, "", nil, err
 	}
+	// A sync lists roles from their first page before their entitlements, so their groups are looked up again.
+	if pageToken == 0 {
+		r.mu.Lock()
+		r.groups = nil
+		r.mu.Unlock()
+	}
 
 	roles, nextPageToken, annotation, err := r.client.ListRoles(ctx, client.PageOptions{
 		PerPage: pToken.Size,
@@ -62,7 +86,9 @@ func (r *roleBuilder) List(ctx context.Context, _ *v2.ResourceId, pToken *pagina
 	return rv, nextPageToken, annotation, nil
 }
 
-func (r *roleBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+// Entitlements returns the assignment of a role, one entitlement per assignment scope, and one entitlement per agent
+// group the role can be scoped to with the specified_groups scope.
+func (r *roleBuilder) Entitlements(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
 	var rv []*v2.Entitlement
 	assigmentOptions := []ent.EntitlementOption{
 		ent.WithGrantableTo(agentUserResourceType),
@@ -71,13 +97,142 @@ func (r *roleBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *
 	}
 	rv = append(rv, ent.NewAssignmentEntitlement(resource, assignedEntitlement, assigmentOptions...))
 
+	rv = append(rv,
+		ent.NewAssignmentEntitlement(resource, assignmentScopeEntireHelpdesk,
+			ent.WithGrantableTo(agentUserResourceType),
+			ent.WithDescription(fmt.Sprintf("Assigned to %s role across the entire helpdesk", resource.DisplayName)),
+			ent.WithDisplayName(fmt.Sprintf("%s role for the entire helpdesk", resource.DisplayName)),
+		),
+		ent.NewAssignmentEntitlement(resource, assignmentScopeMemberGroups,
+			ent.WithGrantableTo(agentUserResourceType),
+			ent.WithDescription(fmt.Sprintf("Assigned to %s role for the groups the agent is a member of", resource.DisplayName)),
+			ent.WithDisplayName(fmt.Sprintf("%s role for member groups", resource.DisplayName)),
+		),
+	)
+
+	groups, err := r.agentGroups(ctx)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	for _, group := range groups {
+		rv = append(rv, ent.NewAssignmentEntitlement(resource, specifiedGroupEntitlement(group.ID),
+			ent.WithGrantableTo(agentUserResourceType),
+			ent.WithDescription(fmt.Sprintf("Assigned to %s role for the %s group", resource.DisplayName, group.Name)),
+			ent.WithDisplayName(fmt.Sprintf("%s role for %s group", resource.DisplayName, group.Name)),
+		))
+	}
+
 	return rv, "", nil, nil
 }
 
+// agentGroups returns the synced agent groups, looking them up for the first role of a sync only.
+func (r *roleBuilder) agentGroups(ctx context.Context) ([]client.AgentGroup, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.groups != nil {
+		return r.groups, nil
+	}
+
+	groups, err := syncedAgentGroups(ctx, r.client)
+	if err != nil {
+		return nil, err
+	}
+	r.groups = groups
+	if r.groups == nil {
+		r.groups = []client.AgentGroup{}
+	}
+	return r.groups, nil
+}
+
+// specifiedGroupEntitlement returns the slug of the entitlement scoping a role to an agent group.
+func specifiedGroupEntitlement(groupID int64) string {
+	return specifiedGroupEntitlementPrefix + strconv.FormatInt(groupID, 10)
+}
+
+// parseRoleEntitlement returns the assignment scope of a role entitlement, empty for the assigned entitlement, and the
+// agent group of a specified_groups entitlement.
+func parseRoleEntitlement(entitlement *v2.Entitlement) (string, int64, error) {
+	return parseRoleSlug(entitlementSlug(entitlement))
+}
+
+// parseRoleSlug returns the assignment scope and specified group of a role entitlement slug, like
+// parseRoleEntitlement.
+func parseRoleSlug(slug string) (string, int64, error) {
+	switch slug {
+	case assignedEntitlement:
+		return "", 0, nil
+	case assignmentScopeEntireHelpdesk, assignmentScopeMemberGroups:
+		return slug, 0, nil
+	}
+
+	groupID, ok := strings.CutPrefix(slug, specifiedGroupEntitlementPrefix)
+	if !ok {
+		return "", 0, fmt.Errorf("freshservice-connector: unknown role entitlement %q", slug)
+	}
+	id, err := strconv.ParseInt(groupID, 10, 64)
+	if err != nil {
+		return "", 0, fmt.Errorf("freshservice-connector: invalid role entitlement %q: %w", slug, err)
+	}
+	return assignmentScopeSpecifiedGroups, id, nil
+}
+
+// roleScopeMetadata describes the assignment scope of a role, and the groups it's limited to, on its grants.
+func roleScopeMetadata(role client.AgentRole) map[string]interface{} {
+	groupIDs := make([]interface{}, 0, len(role.Groups))
+	for _, groupID := range role.Groups {
+		groupIDs = append(groupIDs, groupID)
+	}
+	return map[string]interface{}{
+		"assignment_scope": role.AssignmentScope,
+		"group_ids":        groupIDs,
+	}
+}
+
+// userRoleGrants returns the assignment of a role to an agent, carrying its scope, and the grant of the scope
+// entitlement, or of each specified group, the role is assigned with.
+func userRoleGrants(ctx context.Context, userID *v2.ResourceId, role client.AgentRole) ([]*v2.Grant, error) {
+	roleRes, err := roleResource(ctx, &client.Roles{
+		ID: role.RoleID,
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	rv := []*v2.Grant{
+		grant.NewGrant(roleRes, assignedEntitlement, userID, grant.WithGrantMetadata(roleScopeMetadata(role))),
+	}
+	switch role.AssignmentScope {
+	case assignmentScopeEntireHelpdesk, assignmentScopeMemberGroups:
+		rv = append(rv, grant.NewGrant(roleRes, role.AssignmentScope, userID))
+	case assignmentScopeSpecifiedGroups:
+		for _, groupID := range role.Groups {
+			rv = append(rv, grant.NewGrant(roleRes, specifiedGroupEntitlement(groupID), userID))
+		}
+	}
+	return rv, nil
+}
+
+// holdsRoleScope reports whether an agent's role has the scope of an entitlement, as returned by
+// parseRoleEntitlement. Any scope holds the assigned entitlement.
+func holdsRoleScope(role client.AgentRole, scope string, groupID int64) bool {
+	switch scope {
+	case "":
+		return true
+	case assignmentScopeSpecifiedGroups:
+		return role.AssignmentScope == scope && slices.Contains(role.Groups, groupID)
+	default:
+		return role.AssignmentScope == scope
+	}
+}
+
 func (r *roleBuilder) Grants(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
 	return nil, "", nil, nil
 }
 
+// Grant assigns the role to the agent with the scope of the entitlement. The assigned entitlement uses the
+// member_groups scope for a new role and keeps the scope of a role the agent already has. A specified group
+// entitlement adds the group to a role already scoped to specified groups. Nothing is written when the role already
+// has the scope.
 func (r *roleBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
 	l := ctxzap.Extract(ctx)
 	if principal.Id.ResourceType != agentUserResourceType.Id {
@@ -89,6 +244,11 @@ func (r *roleBuilder) Grant(ctx context.Context, principal *v2.Resource, entitle
 		return nil, fmt.Errorf("freshservice-connector: only users can be granted role membership")
 	}
 
+	scope, groupID, err := parseRoleEntitlement(entitlement)
+	if err != nil {
+		return nil, err
+	}
+
 	roleId := entitlement.Resource.Id.Resource
 	userId := principal.Id.Resource
 	roles, _, err := r.client.GetAgentDetail(ctx, userId)
@@ -102,19 +262,52 @@ func (r *roleBuilder) Grant(ctx context.Context, principal *v2.Resource, entitle
 	}
 
 	var bodyRoles []client.AgentRole
+	found := false
 	for _, role := range roles.Agent.Roles {
-		bodyRoles = append(bodyRoles, client.AgentRole{
+		bodyRole := client.AgentRole{
 			RoleID:          role.RoleID,
 			AssignmentScope: role.AssignmentScope,
 			Groups:          role.Groups,
-		})
+		}
+		if role.RoleID == roleId64 {
+			if holdsRoleScope(role, scope, groupID) {
+				l.Info(
+					"freshservice-connector: role is already assigned with the entitlement's scope",
+					zap.String("entitlement_id", entitlement.Id),
+					zap.String("principal_id", userId),
+					zap.String("assignment_scope", role.AssignmentScope),
+				)
+				return annotations.New(&v2.GrantAlreadyExists{}), nil
+			}
+			found = true
+			switch {
+			case scope != assignmentScopeSpecifiedGroups:
+				bodyRole.AssignmentScope = scope
+				bodyRole.Groups = nil
+			case role.AssignmentScope != assignmentScopeSpecifiedGroups:
+				bodyRole.AssignmentScope = scope
+				bodyRole.Groups = []int64{groupID}
+			default:
+				bodyRole.Groups = append(slices.Clone(role.Groups), groupID)
+			}
+		}
+		bodyRoles = append(bodyRoles, bodyRole)
 	}
 
-	// Adding new role. "member_groups" is setted as the Assignment scope since it make the role valid only for the Agent groups.
-	bodyRoles = append(bodyRoles, client.AgentRole{
-		RoleID:          roleId64,
-		AssignmentScope: "member_groups",
-	})
+	if !found {
+		// "member_groups" is the default assignment scope since it makes the role valid only for the agent's groups.
+		newRole := client.AgentRole{
+			RoleID:          roleId64,
+			AssignmentScope: scope,
+		}
+		switch scope {
+		case "":
+			newRole.AssignmentScope = assignmentScopeMemberGroups
+		case assignmentScopeSpecifiedGroups:
+			newRole.Groups = []int64{groupID}
+		}
+		bodyRoles = append(bodyRoles, newRole)
+	}
 
 	annotation, err := r.client.UpdateAgentRoles(ctx, bodyRoles, userId)
 	if err != nil {
@@ -124,6 +317,9 @@ func (r *roleBuilder) Grant(ctx context.Context, principal *v2.Resource, entitle
 	return annotation, nil
 }
 
+// Revoke unassigns the role from the agent. Revoking a scope only unassigns the role while it still has that scope,
+// and revoking a specified group removes the group, unassigning the role once no group is left. Nothing is written
+// when the agent, or the role with that scope, is already gone.
 func (r *roleBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
 	l := ctxzap.Extract(ctx)
 	principal := grant.Principal
@@ -137,10 +333,23 @@ func (r *roleBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.
 		return nil, fmt.Errorf("freshservice-connector: only users can have role membership revoked")
 	}
 
+	scope, groupID, err := parseRoleEntitlement(entitlement)
+	if err != nil {
+		return nil, err
+	}
+
 	userId := principal.Id.Resource
 	roleId := entitlement.Resource.Id.Resource
 	roles, _, err := r.client.GetAgentDetail(ctx, userId)
-	if err != nil {
+	switch {
+	case client.IsNotFound(err):
+		// The agent was deleted, taking its roles with it.
+		l.Info(
+			"freshservice-connector: agent not found, nothing to revoke",
+			zap.String("principal_id", userId),
+		)
+		return annotations.New(&v2.GrantAlreadyRevoked{}), nil
+	case err != nil:
 		return nil, err
 	}
 
@@ -149,9 +358,23 @@ func (r *roleBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.
 		return nil, err
 	}
 
-	var bodyRoles []client.AgentRole
+	// An empty list, rather than null, unassigns the agent's last role.
+	bodyRoles := []client.AgentRole{}
+	found := false
 	for _, role := range roles.Agent.Roles {
 		if roleId64 == role.RoleID {
+			if !holdsRoleScope(role, scope, groupID) {
+				break
+			}
+			found = true
+
+			if scope == assignmentScopeSpecifiedGroups && len(role.Groups) > 1 {
+				bodyRoles = append(bodyRoles, client.AgentRole{
+					RoleID:          role.RoleID,
+					AssignmentScope: role.AssignmentScope,
+					Groups:          slices.DeleteFunc(slices.Clone(role.Groups), func(id int64) bool { return id == groupID }),
+				})
+			}
 			continue
 		}
 
@@ -162,6 +385,15 @@ func (r *roleBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.
 		})
 	}
 
+	if !found {
+		l.Info(
+			"freshservice-connector: role is no longer assigned with the entitlement's scope",
+			zap.String("entitlement_id", entitlement.Id),
+			zap.String("principal_id", userId),
+		)
+		return annotations.New(&v2.GrantAlreadyRevoked{}), nil
+	}
+
 	annotation, err := r.client.UpdateAgentRoles(ctx, bodyRoles, userId)
 	if err != nil {
 		return nil, err
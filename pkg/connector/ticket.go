@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"strconv"
 	"time"
 
@@ -23,24 +24,40 @@ const ticketUrlFmt = "https://%s.freshservice.com/a/tickets/%d"
 func (c *Connector) ListTicketSchemas(ctx context.Context, pt *pagination.Token) ([]*v2.TicketSchema, string, annotations.Annotations, error) {
 	var ret []*v2.TicketSchema
 
-	page, err := ConvertPageToken(pt.Token)
+	// Service catalog items are listed one configured workspace at a time, or across all workspaces when none are configured.
+	bag := &pagination.Bag{}
+	err := bag.Unmarshal(pt.Token)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if bag.Current() == nil {
+		workspaceIDs := c.client.GetWorkspaceIDs()
+		if len(workspaceIDs) == 0 {
+			bag.Push(pagination.PageState{})
+		}
+		for _, workspaceID := range slices.Backward(workspaceIDs) {
+			bag.Push(pagination.PageState{ResourceTypeID: workspaceResourceType.Id, ResourceID: workspaceID})
+		}
+	}
+
+	page, err := ConvertPageToken(bag.PageToken())
 	if err != nil {
 		return nil, "", nil, err
 	}
 
+	var reqOpts []client.ReqOpt
+	if workspaceID := bag.ResourceID(); workspaceID != "" {
+		reqOpts = append(reqOpts, client.WithWorkspaceID(workspaceID))
+	}
 	serviceCatalogItems, annos, nextPage, err := c.client.ListServiceCatalogItems(ctx, client.PageOptions{
 		PerPage: pt.Size,
 		Page:    page,
-	})
+	}, reqOpts...)
 	if err != nil {
 		return nil, "", nil, fmt.Errorf("freshservice-connector: failed to list service catalog items: %w", err)
 	}
 
-	ticketStatuses, err := c.client.GetTicketStatuses(ctx)
-	if err != nil {
-		return nil, "", nil, fmt.Errorf("freshservice-connector: failed to ticket statuses: %w", err)
-	}
-
+	ticketStatuses := make(map[int][]*v2.TicketStatus)
 	for _, serviceItem := range serviceCatalogItems.ServiceItems {
 		if serviceItem.Deleted || serviceItem.Visibility == client.ServiceItemVisibilityDraft {
 			continue
@@ -49,11 +66,19 @@ func (c *Connector) ListTicketSchemas(ctx context.Context, pt *pagination.Token)
 		if err != nil {
 			return nil, "", nil, err
 		}
-		ticketSchema.Statuses = ticketStatuses
 		ret = append(ret, ticketSchema)
 	}
 
-	return ret, nextPage, annos, nil
+	err = bag.Next(nextPage)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	nextToken, err := bag.Marshal()
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return ret, nextToken, annos, nil
 }
 
 func (c *Connector) GetTicket(ctx context.Context, ticketId string) (*v2.Ticket, annotations.Annotations, error) {
@@ -191,15 +216,30 @@ func (c *Connector) CreateTicket(ctx context.Context, ticket *v2.Ticket, schema
 }
 
 func (c *Connector) GetTicketSchema(ctx context.Context, schemaID string) (*v2.TicketSchema, annotations.Annotations, error) {
-	ticketStatuses, err := c.client.GetTicketStatuses(ctx)
+	ticketSchema, err := c.schemaForServiceCatalogItem(ctx, schemaID, make(map[int][]*v2.TicketStatus))
 	if err != nil {
 		return nil, nil, err
 	}
-	ticketSchema, err := c.schemaForServiceCatalogItem(ctx, schemaID, ticketStatuses)
+	return ticketSchema, nil, nil
+}
+
+// ticketStatuses returns the ticket statuses of a workspace, fetching them once per workspace into statuses.
+func (c *Connector) ticketStatuses(ctx context.Context, workspaceID int, statuses map[int][]*v2.TicketStatus) ([]*v2.TicketStatus, error) {
+	if ticketStatuses, ok := statuses[workspaceID]; ok {
+		return ticketStatuses, nil
+	}
+
+	var reqOpts []client.ReqOpt
+	if workspaceID != 0 {
+		reqOpts = append(reqOpts, client.WithWorkspaceID(strconv.Itoa(workspaceID)))
+	}
+	ticketStatuses, err := c.client.GetTicketStatuses(ctx, reqOpts...)
 	if err != nil {
-		return nil, nil, err
+		return nil, fmt.Errorf("freshservice-connector: failed to get ticket statuses: %w", err)
 	}
-	return ticketSchema, nil, nil
+	statuses[workspaceID] = ticketStatuses
+
+	return ticketStatuses, nil
 }
 
 func (c *Connector) BulkCreateTickets(ctx context.Context, request *v2.TicketsServiceBulkCreateTicketsRequest) (*v2.TicketsServiceBulkCreateTicketsResponse, error) {
@@ -245,12 +285,19 @@ func (c *Connector) BulkGetTickets(ctx context.Context, request *v2.TicketsServi
 	return &v2.TicketsServiceBulkGetTicketsResponse{Tickets: tickets}, nil
 }
 
-func (c *Connector) schemaForServiceCatalogItem(ctx context.Context, schemaID string, ticketStatuses []*v2.TicketStatus) (*v2.TicketSchema, error) {
+func (c *Connector) schemaForServiceCatalogItem(ctx context.Context, schemaID string, statuses map[int][]*v2.TicketStatus) (*v2.TicketSchema, error) {
 	l := ctxzap.Extract(ctx)
 	serviceItem, err := c.client.GetServiceItem(ctx, schemaID)
 	if err != nil {
 		return nil, fmt.Errorf("freshservice-connector: failed to get service item %s: %w", schemaID, err)
 	}
+	if !isWorkspaceSelected(c.client, int64(serviceItem.WorkspaceID)) {
+		return nil, fmt.Errorf("freshservice-connector: service item %s belongs to workspace %d, which is not configured", schemaID, serviceItem.WorkspaceID)
+	}
+	ticketStatuses, err := c.ticketStatuses(ctx, serviceItem.WorkspaceID, statuses)
+	if err != nil {
+		return nil, err
+	}
 	customFields := make(map[string]*v2.TicketCustomField)
 	for _, cf := range serviceItem.CustomFields {
 		if cf.Deleted {
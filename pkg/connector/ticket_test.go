@@ -0,0 +1,109 @@
+package connector
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/conductorone/baton-freshservice/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	sdkTicket "github.com/conductorone/baton-sdk/pkg/types/ticket"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTicketingFlow(t *testing.T) {
+	srv, c := newTestTenant(t)
+	srv.AddServiceItem(client.ServiceItem{
+		ID:         500,
+		DisplayID:  5,
+		Name:       "Laptop",
+		Visibility: 2,
+		CustomFields: []client.CustomField{
+			{ID: "cf_1", Name: "justification", Label: "Justification", FieldType: "custom_text", Required: true},
+		},
+	})
+	srv.AddServiceItem(client.ServiceItem{ID: 501, DisplayID: 6, Name: "Draft item", Visibility: client.ServiceItemVisibilityDraft})
+	conn := &Connector{client: c}
+
+	schemas, next, _, err := conn.ListTicketSchemas(ctxTest, &pagination.Token{})
+	require.NoError(t, err)
+	require.Empty(t, next)
+	require.Len(t, schemas, 1)
+	schema := schemas[0]
+	require.Equal(t, "5", schema.Id)
+	require.Contains(t, schema.CustomFields, "justification")
+	require.Len(t, schema.Statuses, 4)
+
+	requestedFor, err := requesterUserResource(ctxTest, &client.Requesters{ID: 101, PrimaryEmail: "rae@example.com"}, nil)
+	require.NoError(t, err)
+	created, _, err := conn.CreateTicket(ctxTest, &v2.Ticket{
+		DisplayName:  "New laptop for Rae",
+		Description:  "Onboarding",
+		Labels:       []string{"onboarding"},
+		RequestedFor: requestedFor,
+		CustomFields: map[string]*v2.TicketCustomField{
+			"justification": sdkTicket.StringField("justification", "new hire"),
+		},
+	}, schema)
+	require.NoError(t, err)
+	require.Equal(t, "New laptop for Rae", created.DisplayName)
+	require.Equal(t, []string{"onboarding"}, created.Labels)
+
+	id, err := strconv.Atoi(created.Id)
+	require.NoError(t, err)
+	stored, ok := srv.Ticket(id)
+	require.True(t, ok)
+	require.Equal(t, map[string]interface{}{"justification": "new hire"}, stored.CustomFields)
+
+	fetched, _, err := conn.GetTicket(ctxTest, created.Id)
+	require.NoError(t, err)
+	require.Equal(t, "New laptop for Rae", fetched.DisplayName)
+	require.Equal(t, "Onboarding", fetched.Description)
+	require.Equal(t, "2", fetched.Status.Id)
+}
+
+func TestCreateTicketRejectsMissingRequiredField(t *testing.T) {
+	srv, c := newTestTenant(t)
+	srv.AddServiceItem(client.ServiceItem{
+		ID:        500,
+		DisplayID: 5,
+		Name:      "Laptop",
+		CustomFields: []client.CustomField{
+			{ID: "cf_1", Name: "justification", Label: "Justification", FieldType: "custom_text", Required: true},
+		},
+	})
+	conn := &Connector{client: c}
+
+	schema, _, err := conn.GetTicketSchema(ctxTest, "5")
+	require.NoError(t, err)
+
+	_, _, err = conn.CreateTicket(ctxTest, &v2.Ticket{DisplayName: "Missing justification"}, schema)
+	require.Error(t, err)
+}
+
+func TestTicketSchemasAreRestrictedToConfiguredWorkspaces(t *testing.T) {
+	srv, c := newTestTenant(t)
+	srv.AddServiceItem(client.ServiceItem{ID: 500, DisplayID: 5, Name: "Laptop", WorkspaceID: 2, Visibility: 2})
+	srv.AddServiceItem(client.ServiceItem{ID: 501, DisplayID: 6, Name: "Badge", WorkspaceID: 3, Visibility: 2})
+	srv.AddServiceItem(client.ServiceItem{ID: 502, DisplayID: 7, Name: "Desk", WorkspaceID: 4, Visibility: 2})
+	c.WithWorkspaceIDs([]string{"2", "3"})
+	conn := &Connector{client: c}
+
+	var schemaIDs []string
+	token := &pagination.Token{}
+	for {
+		schemas, next, _, err := conn.ListTicketSchemas(ctxTest, token)
+		require.NoError(t, err)
+		for _, schema := range schemas {
+			schemaIDs = append(schemaIDs, schema.Id)
+		}
+		if next == "" {
+			break
+		}
+		token = &pagination.Token{Token: next}
+	}
+	require.Equal(t, []string{"5", "6"}, schemaIDs)
+
+	_, _, err := conn.GetTicketSchema(ctxTest, "7")
+	require.Error(t, err)
+}
@@ -0,0 +1,317 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/conductorone/baton-freshservice/pkg/client"
+	config "github.com/conductorone/baton-sdk/pb/c1/config/v1"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/actions"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Resource actions on agents and requesters. Each takes the user as its resource_id argument and returns the user,
+// as it is after the action, as its resource.
+const (
+	convertToAgentAction     = "convert_to_agent"
+	convertToRequesterAction = "convert_to_requester"
+	disableAction            = "disable"
+	enableAction             = "enable"
+
+	resourceIDArg    = "resource_id"
+	occasionalArg    = "occasional"
+	resourceReturned = "resource"
+)
+
+var convertToAgentSchema = &v2.BatonActionSchema{
+	Name:        convertToAgentAction,
+	DisplayName: "Convert to agent",
+	Description: "Converts the requester to an agent with the same ID, keeping its ticket history. The agent holds a " +
+		"full-time license unless it is made an occasional agent.",
+	Arguments: []*config.Field{
+		{
+			Name:        resourceIDArg,
+			DisplayName: "Requester",
+			IsRequired:  true,
+			Field:       &config.Field_ResourceIdField{ResourceIdField: &config.ResourceIdField{}},
+		},
+		{
+			Name:        occasionalArg,
+			DisplayName: "Occasional agent",
+			Description: "Whether the agent uses day passes instead of a full-time license.",
+			Field:       &config.Field_BoolField{BoolField: &config.BoolField{}},
+		},
+	},
+	ReturnTypes: []*config.Field{
+		{Name: "success", DisplayName: "Success", Field: &config.Field_BoolField{BoolField: &config.BoolField{}}},
+		{Name: resourceReturned, DisplayName: "Agent", Field: &config.Field_ResourceField{ResourceField: &config.ResourceField{}}},
+	},
+	ActionType: []v2.ActionType{v2.ActionType_ACTION_TYPE_DYNAMIC},
+}
+
+var convertToRequesterSchema = &v2.BatonActionSchema{
+	Name:        convertToRequesterAction,
+	DisplayName: "Convert to requester",
+	Description: "Converts the agent to a requester with the same ID, releasing its license and keeping its ticket " +
+		"history. The requester leaves the agent groups of the agent.",
+	Arguments: []*config.Field{
+		{
+			Name:        resourceIDArg,
+			DisplayName: "Agent",
+			IsRequired:  true,
+			Field:       &config.Field_ResourceIdField{ResourceIdField: &config.ResourceIdField{}},
+		},
+	},
+	ReturnTypes: []*config.Field{
+		{Name: "success", DisplayName: "Success", Field: &config.Field_BoolField{BoolField: &config.BoolField{}}},
+		{Name: resourceReturned, DisplayName: "Requester", Field: &config.Field_ResourceField{ResourceField: &config.ResourceField{}}},
+	},
+	ActionType: []v2.ActionType{v2.ActionType_ACTION_TYPE_DYNAMIC},
+}
+
+// userStatusSchema returns the schema of the action disabling, or enabling, users of resourceType. Registering a
+// schema scopes it to a resource type, so each type gets its own.
+func userStatusSchema(resourceType *v2.ResourceType, enable bool) *v2.BatonActionSchema {
+	schema := &v2.BatonActionSchema{
+		Name:        disableAction,
+		DisplayName: "Disable",
+		Description: fmt.Sprintf("Deactivates the %s, which can't sign in until it is enabled again. Its tickets are kept.", resourceType.Id),
+		ActionType:  []v2.ActionType{v2.ActionType_ACTION_TYPE_ACCOUNT_DISABLE},
+	}
+	if enable {
+		schema.Name = enableAction
+		schema.DisplayName = "Enable"
+		schema.Description = fmt.Sprintf("Reactivates the deactivated %s.", resourceType.Id)
+		schema.ActionType = []v2.ActionType{v2.ActionType_ACTION_TYPE_ACCOUNT_ENABLE}
+	}
+	schema.Arguments = []*config.Field{
+		{
+			Name:        resourceIDArg,
+			DisplayName: resourceType.DisplayName,
+			IsRequired:  true,
+			Field:       &config.Field_ResourceIdField{ResourceIdField: &config.ResourceIdField{}},
+		},
+	}
+	schema.ReturnTypes = []*config.Field{
+		{Name: "success", DisplayName: "Success", Field: &config.Field_BoolField{BoolField: &config.BoolField{}}},
+		{Name: resourceReturned, DisplayName: resourceType.DisplayName, Field: &config.Field_ResourceField{ResourceField: &config.ResourceField{}}},
+	}
+	return schema
+}
+
+// actionUserID returns the ID of the user an action is invoked on, which must be of resourceType.
+func actionUserID(args *structpb.Struct, resourceType *v2.ResourceType) (string, error) {
+	resourceID, err := actions.RequireResourceIDArg(args, resourceIDArg)
+	if err != nil {
+		return "", fmt.Errorf("freshservice-connector: %w", err)
+	}
+	if resourceID.ResourceType != resourceType.Id {
+		return "", fmt.Errorf("freshservice-connector: expected a %s, not a %s", resourceType.Id, resourceID.ResourceType)
+	}
+	return resourceID.Resource, nil
+}
+
+// userActionResult returns the result of an action on a user, as it is after the action.
+func userActionResult(resource *v2.Resource) (*structpb.Struct, error) {
+	field, err := actions.NewResourceReturnField(resourceReturned, resource)
+	if err != nil {
+		return nil, err
+	}
+	return actions.NewReturnValues(true, field), nil
+}
+
+// convertToAgent converts a requester to an agent, and makes it a full-time agent unless it should be occasional.
+// A requester converted before is already an agent with its ID, which is returned as is.
+func (u *requesterUserBuilder) convertToAgent(ctx context.Context, args *structpb.Struct) (*structpb.Struct, annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+	requesterId, err := actionUserID(args, requesterResourceType)
+	if err != nil {
+		return nil, nil, err
+	}
+	occasional, _ := actions.GetBoolArg(args, occasionalArg)
+
+	var annos annotations.Annotations
+	_, _, err = u.client.GetRequesterDetail(ctx, requesterId)
+	switch {
+	case client.IsNotFound(err):
+		l.Info("freshservice-connector: requester was already converted to an agent", zap.String("requester_id", requesterId))
+	case err != nil:
+		return nil, nil, err
+	default:
+		annos, err = u.client.ConvertRequesterToAgent(ctx, requesterId)
+		if err != nil {
+			l.Warn(
+				"freshservice-connector: failed to convert requester to agent",
+				zap.String("requester_id", requesterId),
+				zap.Error(err),
+			)
+			return nil, nil, fmt.Errorf("freshservice-connector: failed to convert requester %s to an agent: %w", requesterId, err)
+		}
+		if !occasional {
+			_, err = u.client.UpdateAgentOccasional(ctx, requesterId, false)
+			if err != nil {
+				l.Warn(
+					"freshservice-connector: failed to give converted agent a full-time license",
+					zap.String("agent_id", requesterId),
+					zap.Error(err),
+				)
+				return nil, nil, fmt.Errorf("freshservice-connector: failed to give agent %s a full-time license: %w", requesterId, err)
+			}
+		}
+	}
+
+	agent, _, err := u.client.GetAgentDetail(ctx, requesterId)
+	if err != nil {
+		return nil, nil, err
+	}
+	resource, err := agentResource(ctx, &agent.Agent, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	rv, err := userActionResult(resource)
+	if err != nil {
+		return nil, nil, err
+	}
+	return rv, annos, nil
+}
+
+// convertToRequester converts an agent to a requester. An agent converted before is already a requester with its ID,
+// which is returned as is.
+func (u *agentUserBuilder) convertToRequester(ctx context.Context, args *structpb.Struct) (*structpb.Struct, annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+	agentId, err := actionUserID(args, agentUserResourceType)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var annos annotations.Annotations
+	_, _, err = u.client.GetAgentDetail(ctx, agentId)
+	switch {
+	case client.IsNotFound(err):
+		l.Info("freshservice-connector: agent was already converted to a requester", zap.String("agent_id", agentId))
+	case err != nil:
+		return nil, nil, err
+	default:
+		annos, err = u.client.ConvertAgentToRequester(ctx, agentId)
+		if err != nil {
+			l.Warn(
+				"freshservice-connector: failed to convert agent to requester",
+				zap.String("agent_id", agentId),
+				zap.Error(err),
+			)
+			return nil, nil, fmt.Errorf("freshservice-connector: failed to convert agent %s to a requester: %w", agentId, err)
+		}
+	}
+
+	requester, _, err := u.client.GetRequesterDetail(ctx, agentId)
+	if err != nil {
+		return nil, nil, err
+	}
+	resource, err := requesterUserResource(ctx, &requester.Requester, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	rv, err := userActionResult(resource)
+	if err != nil {
+		return nil, nil, err
+	}
+	return rv, annos, nil
+}
+
+// setStatus deactivates or reactivates an agent. Nothing is written when the agent already has the status.
+func (u *agentUserBuilder) setStatus(ctx context.Context, args *structpb.Struct, active bool) (*structpb.Struct, annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+	agentId, err := actionUserID(args, agentUserResourceType)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	agent, _, err := u.client.GetAgentDetail(ctx, agentId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var annos annotations.Annotations
+	switch {
+	case agent.Agent.Active == active:
+		l.Info("freshservice-connector: agent already has the status", zap.String("agent_id", agentId), zap.Bool("active", active))
+	case active:
+		annos, err = u.client.ReactivateAgent(ctx, agentId)
+	default:
+		annos, err = u.client.DeactivateAgent(ctx, agentId)
+	}
+	if err != nil {
+		l.Warn(
+			"freshservice-connector: failed to change agent status",
+			zap.String("agent_id", agentId),
+			zap.Bool("active", active),
+			zap.Error(err),
+		)
+		return nil, nil, fmt.Errorf("freshservice-connector: failed to change the status of agent %s: %w", agentId, err)
+	}
+
+	agent, _, err = u.client.GetAgentDetail(ctx, agentId)
+	if err != nil {
+		return nil, nil, err
+	}
+	resource, err := agentResource(ctx, &agent.Agent, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	rv, err := userActionResult(resource)
+	if err != nil {
+		return nil, nil, err
+	}
+	return rv, annos, nil
+}
+
+// setStatus deactivates or reactivates a requester. Nothing is written when the requester already has the status.
+func (u *requesterUserBuilder) setStatus(ctx context.Context, args *structpb.Struct, active bool) (*structpb.Struct, annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+	requesterId, err := actionUserID(args, requesterResourceType)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	requester, _, err := u.client.GetRequesterDetail(ctx, requesterId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var annos annotations.Annotations
+	switch {
+	case requester.Requester.Active == active:
+		l.Info("freshservice-connector: requester already has the status", zap.String("requester_id", requesterId), zap.Bool("active", active))
+	case active:
+		annos, err = u.client.ReactivateRequester(ctx, requesterId)
+	default:
+		annos, err = u.client.DeactivateRequester(ctx, requesterId)
+	}
+	if err != nil {
+		l.Warn(
+			"freshservice-connector: failed to change requester status",
+			zap.String("requester_id", requesterId),
+			zap.Bool("active", active),
+			zap.Error(err),
+		)
+		return nil, nil, fmt.Errorf("freshservice-connector: failed to change the status of requester %s: %w", requesterId, err)
+	}
+
+	requester, _, err = u.client.GetRequesterDetail(ctx, requesterId)
+	if err != nil {
+		return nil, nil, err
+	}
+	resource, err := requesterUserResource(ctx, &requester.Requester, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	rv, err := userActionResult(resource)
+	if err != nil {
+		return nil, nil, err
+	}
+	return rv, annos, nil
+}
@@ -0,0 +1,86 @@
+package connector
+
+import (
+	"context"
+	"slices"
+	"strconv"
+
+	"github.com/conductorone/baton-freshservice/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+)
+
+type workspaceBuilder struct {
+	resourceType *v2.ResourceType
+	client       *client.FreshServiceClient
+}
+
+func (w *workspaceBuilder) ResourceType(_ context.Context) *v2.ResourceType {
+	return workspaceResourceType
+}
+
+// List returns the workspaces of the account, limited to the configured workspace IDs if any.
+// Workspaces are the parents of agent groups.
+func (w *workspaceBuilder) List(ctx context.Context, _ *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	var rv []*v2.Resource
+	bag, pageToken, err := getToken(pToken, workspaceResourceType)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	workspaces, nextPageToken, annotation, err := w.client.ListWorkspaces(ctx, client.PageOptions{
+		PerPage: pToken.Size,
+		Page:    pageToken,
+	})
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	err = bag.Next(nextPageToken)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	for _, workspace := range workspaces.Workspaces {
+		if !isWorkspaceSelected(w.client, workspace.ID) {
+			continue
+		}
+		workspaceCopy := workspace
+		wr, err := workspaceResource(ctx, &workspaceCopy)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		rv = append(rv, wr)
+	}
+
+	nextPageToken, err = bag.Marshal()
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return rv, nextPageToken, annotation, nil
+}
+
+// Entitlements always returns an empty slice for workspaces.
+func (w *workspaceBuilder) Entitlements(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// Grants always returns an empty slice for workspaces since they don't have any entitlements.
+func (w *workspaceBuilder) Grants(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// isWorkspaceSelected reports whether a workspace is in the configured workspace IDs, or whether all workspaces are used.
+func isWorkspaceSelected(c *client.FreshServiceClient, workspaceID int64) bool {
+	workspaceIDs := c.GetWorkspaceIDs()
+	return len(workspaceIDs) == 0 || slices.Contains(workspaceIDs, strconv.FormatInt(workspaceID, 10))
+}
+
+func newWorkspaceBuilder(c *client.FreshServiceClient) *workspaceBuilder {
+	return &workspaceBuilder{
+		resourceType: workspaceResourceType,
+		client:       c,
+	}
+}